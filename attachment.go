@@ -0,0 +1,360 @@
+package gomailer
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+const (
+	// DispositionAttachment 普通附件，收件人需要单独打开或下载
+	DispositionAttachment = "attachment"
+
+	// DispositionInline 内联附件，通常是在 HTML 中通过 "cid:" 引用的图片
+	DispositionInline = "inline"
+)
+
+// Attachment 描述一个带有完整元数据的附件
+//
+// 与 Message.Attachments/InlineAttachments 两个映射相比，Attachment 可以显式指定
+// MIME 类型、Content-ID 和处置方式，并且在 Message.Files 中保持添加时的顺序
+//
+// 内容来源为以下之一：
+//   - Reader：实现了 io.Seeker 时（如 *os.File）流式发送，否则整封邮件会先缓存在内存中
+//   - ReaderAt + Size：从偏移 0 开始读取 Size 个字节，流式发送
+//   - Path：本地文件路径；同时设置 FS 时为 FS 中的文件名。文件在写出时才打开，写完立即关闭
+//
+// 流式发送的附件不会被完整读入内存，但计算邮件大小（MaxMessageSize 或服务器的 SIZE 扩展）
+// 时需要额外读取一遍
+type Attachment struct {
+	// Filename 收件人看到的文件名；为空时使用 Path 的文件名部分
+	Filename string `json:"filename"`
+
+	// ContentType MIME 类型（如 "text/csv"）；为空时先根据扩展名推断，再根据内容检测
+	ContentType string `json:"contentType,omitempty"`
+
+	// ContentID 内联附件的 Content-ID（尖括号可省略）；为空时使用 Filename
+	ContentID string `json:"contentId,omitempty"`
+
+	// Disposition 处置方式：DispositionAttachment（默认）或 DispositionInline
+	Disposition string `json:"disposition,omitempty"`
+
+	// Reader 附件内容
+	Reader io.Reader `json:"-"`
+
+	// ReaderAt 可随机读取的附件内容，需要同时设置 Size
+	ReaderAt io.ReaderAt `json:"-"`
+
+	// Path 附件文件的路径（设置 FS 时为 FS 中的文件名）
+	Path string `json:"path,omitempty"`
+
+	// FS 可选的文件系统（如 embed.FS），设置后从中打开 Path
+	FS fs.FS `json:"-"`
+
+	// Size 附件大小（字节），大于 0 时写入 Content-Disposition 的 size 参数；
+	// 使用 ReaderAt 时必须设置
+	Size int64 `json:"size,omitempty"`
+
+	// offset Reader 实现了 io.Seeker 时，渲染开始时的读取位置
+	offset int64
+}
+
+// AttachFile 添加一个本地文件作为普通附件
+//
+// 文件在发送时才打开，写入完成（无论成功与否）后立即关闭，调用方无需管理文件句柄；
+// 文件名取路径的最后一段，MIME 类型根据扩展名和内容自动推断
+//
+// 参数:
+//   - path: 文件路径
+func (m *Message) AttachFile(path string) {
+	m.Files = append(m.Files, Attachment{Path: path})
+}
+
+// AttachFS 从文件系统（如 embed.FS）中添加一个普通附件
+//
+// 参数:
+//   - fsys: 文件系统
+//   - name: 文件在 fsys 中的名称（使用 "/" 分隔）
+func (m *Message) AttachFS(fsys fs.FS, name string) {
+	m.Files = append(m.Files, Attachment{Path: name, FS: fsys})
+}
+
+// EmbedFile 添加一个本地文件作为内联附件，可以在 HTML 中通过 "cid:文件名" 引用
+//
+// 参数:
+//   - path: 文件路径，Content-ID 为路径的最后一段（如 "images/logo.png" 对应 "cid:logo.png"）
+func (m *Message) EmbedFile(path string) {
+	m.Files = append(m.Files, Attachment{Path: path, Disposition: DispositionInline})
+}
+
+// EmbedFS 从文件系统（如 embed.FS）中添加一个内联附件，可以在 HTML 中通过 "cid:文件名" 引用
+//
+// 示例:
+//
+//	//go:embed assets/logo.png
+//	var assets embed.FS
+//
+//	m.HTML = `<img src="cid:logo.png">`
+//	m.EmbedFS(assets, "assets/logo.png")
+//
+// 参数:
+//   - fsys: 文件系统
+//   - name: 文件在 fsys 中的名称，Content-ID 为其最后一段
+func (m *Message) EmbedFS(fsys fs.FS, name string) {
+	m.Files = append(m.Files, Attachment{Path: name, FS: fsys, Disposition: DispositionInline})
+}
+
+// name 返回附件的文件名
+func (a *Attachment) name() string {
+	if a.Filename == "" && a.Path != "" {
+		return path.Base(filepath.ToSlash(a.Path))
+	}
+	return a.Filename
+}
+
+// inline 返回附件是否为内联附件
+func (a *Attachment) inline() bool {
+	return strings.EqualFold(a.Disposition, DispositionInline)
+}
+
+// contentID 返回内联附件的 Content-ID（不含尖括号）
+func (a *Attachment) contentID() string {
+	id := strings.TrimSpace(a.ContentID)
+	if id == "" {
+		return a.name()
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(id, "<"), ">")
+}
+
+// replayable 返回附件内容是否可以重复读取（即可以流式发送）
+func (a *Attachment) replayable() bool {
+	if a.Reader == nil {
+		return true
+	}
+
+	_, ok := a.Reader.(io.Seeker)
+	return ok
+}
+
+// prepare 在渲染前检查附件来源：记录 io.Seeker 的当前位置，
+// 并确认 Path 指向的文件存在，避免在传输过程中才发现问题
+func (a *Attachment) prepare() error {
+	switch {
+	case a.Reader != nil:
+		if s, ok := a.Reader.(io.Seeker); ok {
+			offset, err := s.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return fmt.Errorf("attachment %q: %w", a.name(), err)
+			}
+			a.offset = offset
+		}
+	case a.ReaderAt != nil:
+	case a.FS != nil:
+		if _, err := fs.Stat(a.FS, a.Path); err != nil {
+			return fmt.Errorf("open attachment %q: %w", a.name(), err)
+		}
+	default:
+		if _, err := os.Stat(a.Path); err != nil {
+			return fmt.Errorf("open attachment %q: %w", a.name(), err)
+		}
+	}
+
+	return nil
+}
+
+// open 打开附件内容，返回的关闭函数在写入完成后调用
+//
+// 可重放的来源每次调用都从头读取
+func (a *Attachment) open() (io.Reader, func() error, error) {
+	noClose := func() error { return nil }
+
+	switch {
+	case a.Reader != nil:
+		if s, ok := a.Reader.(io.Seeker); ok {
+			if _, err := s.Seek(a.offset, io.SeekStart); err != nil {
+				return nil, nil, fmt.Errorf("attachment %q: %w", a.name(), err)
+			}
+		}
+		return a.Reader, noClose, nil
+	case a.ReaderAt != nil:
+		return io.NewSectionReader(a.ReaderAt, 0, a.Size), noClose, nil
+	}
+
+	var f io.ReadCloser
+	var err error
+	if a.FS != nil {
+		f, err = a.FS.Open(a.Path)
+	} else {
+		f, err = os.Open(a.Path)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("open attachment %q: %w", a.name(), err)
+	}
+
+	return f, f.Close, nil
+}
+
+// validate 检查附件的元数据，问题记录到 errs
+func (a *Attachment) validate(errs *ValidationErrors, field string) {
+	sources := 0
+	for _, set := range []bool{a.Reader != nil, a.ReaderAt != nil, a.Path != ""} {
+		if set {
+			sources++
+		}
+	}
+
+	switch {
+	case sources == 0:
+		errs.add(field, "one of reader, readerAt or path is required")
+	case sources > 1:
+		errs.add(field, "reader, readerAt and path are mutually exclusive")
+	case a.FS != nil && !fs.ValidPath(a.Path):
+		errs.add(field+".path", "invalid fs path %q", a.Path)
+	case a.ReaderAt != nil && a.Size <= 0:
+		errs.add(field+".size", "size is required when readerAt is set")
+	}
+
+	if a.name() == "" {
+		errs.add(field, "filename is required")
+	} else if err := checkHeaderValue("Content-Disposition", a.name()); err != nil {
+		errs.addErr(field+".filename", err)
+	}
+
+	if a.ContentType != "" {
+		if _, _, err := mime.ParseMediaType(a.ContentType); err != nil {
+			errs.add(field+".contentType", "invalid content type %q", a.ContentType)
+		}
+	}
+
+	if a.Disposition != "" && !strings.EqualFold(a.Disposition, DispositionAttachment) && !a.inline() {
+		errs.add(field+".disposition", "unknown disposition %q", a.Disposition)
+	}
+
+	if a.ContentID != "" && !validContentID(a.ContentID) {
+		errs.add(field+".contentId", "invalid content id %q", a.ContentID)
+	}
+
+	if a.Size < 0 {
+		errs.add(field+".size", "size must not be negative")
+	}
+}
+
+// validContentID 检查 Content-ID 是否可以安全地写入邮件头（尖括号可省略）
+func validContentID(id string) bool {
+	id = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(id), "<"), ">")
+	if id == "" {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if c := id[i]; c <= 32 || c >= 127 || c == '<' || c == '>' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// extensionTypes 补充常见但标准库不一定内置的扩展名映射
+//
+// mime.TypeByExtension 依赖系统的 mime.types，不同机器的结果可能不同，
+// 这里的类型优先使用，以保证 .csv、.ics 等常见附件在任何环境下都能得到正确的类型
+var extensionTypes = map[string]string{
+	".csv":  "text/csv",
+	".ics":  "text/calendar",
+	".txt":  "text/plain",
+	".md":   "text/markdown",
+	".eml":  "message/rfc822",
+	".vcf":  "text/vcard",
+	".zip":  "application/zip",
+	".doc":  "application/msword",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xls":  "application/vnd.ms-excel",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".ppt":  "application/vnd.ms-powerpoint",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+}
+
+// typeByExtension 根据文件扩展名推断 MIME 类型，无法推断时返回空字符串
+func typeByExtension(filename string) string {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext == "" {
+		return ""
+	}
+
+	if t, ok := extensionTypes[ext]; ok {
+		return t
+	}
+
+	return mime.TypeByExtension(ext)
+}
+
+// resolveContentType 确定附件的 MIME 类型：显式指定 > 扩展名 > 内容检测
+//
+// 内容检测只读取开头的少量字节：只能读取一次的 Reader 会被替换为包含已读取部分的组合 Reader，
+// 其它来源会临时打开后关闭
+func (a *Attachment) resolveContentType() (string, error) {
+	if a.ContentType != "" {
+		return a.ContentType, nil
+	}
+
+	if t := typeByExtension(a.name()); t != "" {
+		return t, nil
+	}
+
+	if !a.replayable() {
+		r, t, err := detectReaderMimeType(a.Reader)
+		if err != nil {
+			return "", err
+		}
+		a.Reader = r
+		return t, nil
+	}
+
+	r, closeFn, err := a.open()
+	if err != nil {
+		return "", err
+	}
+	defer closeFn()
+
+	t, err := mimetype.DetectReader(r)
+	if err != nil {
+		return "", err
+	}
+
+	return t.String(), nil
+}
+
+// messageAttachments 返回邮件全部附件的副本：先是 Files（保持顺序），
+// 然后是兼容的 InlineAttachments/Attachments 映射（按文件名排序）
+func messageAttachments(m *Message) []Attachment {
+	result := make([]Attachment, 0, len(m.Files)+len(m.Attachments)+len(m.InlineAttachments))
+	result = append(result, m.Files...)
+
+	for _, group := range []struct {
+		files       map[string]io.Reader
+		disposition string
+	}{
+		{m.InlineAttachments, DispositionInline},
+		{m.Attachments, DispositionAttachment},
+	} {
+		for _, name := range slices.Sorted(maps.Keys(group.files)) {
+			result = append(result, Attachment{
+				Filename:    name,
+				Disposition: group.disposition,
+				Reader:      group.files[name],
+			})
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,123 @@
+package gomailer
+
+import (
+	"io"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// largeAttachmentSize 大附件测试使用的附件大小
+const largeAttachmentSize = 64 << 20
+
+// maxStreamingAlloc 流式写出一封大附件邮件允许分配的内存，与附件大小无关
+const maxStreamingAlloc = 4 << 20
+
+// patternReaderAt 是一个不占用内存的 io.ReaderAt，内容为按偏移生成的字节
+type patternReaderAt struct{}
+
+// ReadAt 实现 io.ReaderAt 接口
+func (patternReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	for i := range p {
+		p[i] = byte((off + int64(i)) % 251)
+	}
+	return len(p), nil
+}
+
+// largeReaderAtAttachment 返回内容按需生成、不占用内存的大附件
+func largeReaderAtAttachment() Attachment {
+	return Attachment{Filename: "export.bin", ReaderAt: patternReaderAt{}, Size: largeAttachmentSize}
+}
+
+// largeAttachmentSources 返回大附件的各种流式来源，Path 来源会写入一个临时文件
+func largeAttachmentSources(tb testing.TB) map[string]Attachment {
+	tb.Helper()
+
+	path := filepath.Join(tb.TempDir(), "export.bin")
+	f, err := os.Create(path)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if _, err := io.Copy(f, io.NewSectionReader(patternReaderAt{}, 0, largeAttachmentSize)); err != nil {
+		f.Close()
+		tb.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		tb.Fatal(err)
+	}
+
+	return map[string]Attachment{
+		"ReaderAt": largeReaderAtAttachment(),
+		"Path":     {Path: path},
+	}
+}
+
+// writeLargeAttachment 渲染带附件的邮件，先计算大小再写入 io.Discard
+func writeLargeAttachment(tb testing.TB, a Attachment) {
+	m := &Message{
+		From:  mail.Address{Address: "sender@example.com"},
+		To:    []mail.Address{{Address: "to@example.com"}},
+		Text:  "see attachment",
+		Files: []Attachment{a},
+	}
+
+	header, err := buildHeader(m, "bench@example.com")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	r, err := renderMessage(header, m)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	size, err := r.Size()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	n, err := r.WriteTo(io.Discard)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	// base64 编码后约为原大小的 4/3
+	if n != size || n < largeAttachmentSize*4/3 {
+		tb.Fatalf("wrote %d bytes, Size() = %d", n, size)
+	}
+}
+
+// allocatedBytes 返回 fn 执行期间分配的内存总量
+func allocatedBytes(fn func()) uint64 {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	fn()
+	runtime.ReadMemStats(&after)
+	return after.TotalAlloc - before.TotalAlloc
+}
+
+func TestLargeAttachmentStreamsWithoutBuffering(t *testing.T) {
+	alloc := allocatedBytes(func() { writeLargeAttachment(t, largeReaderAtAttachment()) })
+	if alloc > maxStreamingAlloc {
+		t.Errorf("allocated %d bytes for a %d byte attachment, want at most %d", alloc, largeAttachmentSize, maxStreamingAlloc)
+	}
+}
+
+func BenchmarkWriteLargeAttachment(b *testing.B) {
+	for name, a := range largeAttachmentSources(b) {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(largeAttachmentSize)
+
+			alloc := allocatedBytes(func() {
+				for i := 0; i < b.N; i++ {
+					writeLargeAttachment(b, a)
+				}
+			})
+
+			if perOp := alloc / uint64(b.N); perOp > maxStreamingAlloc {
+				b.Fatalf("allocated %d bytes/op for a %d byte attachment, want at most %d", perOp, largeAttachmentSize, maxStreamingAlloc)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package gomailer
+
+import (
+	"io"
+	"io/fs"
+	"net/mail"
+	"strconv"
+)
+
+// MessageBuilder 提供了链式构建 Message 的方式
+//
+// 地址参数使用 RFC 5322 格式的字符串（如 "张三 <zhangsan@example.com>"，
+// 也可以是逗号分隔的多个地址），解析失败的问题会在 Build 时与 Message.Validate
+// 发现的问题一起返回
+//
+// 示例:
+//
+//	msg, err := NewMessage().
+//		From("系统通知 <noreply@example.com>").
+//		To("user@example.com").
+//		Subject("欢迎").
+//		HTML("<p>你好</p>").
+//		Build()
+type MessageBuilder struct {
+	msg  *Message
+	errs ValidationErrors
+}
+
+// NewMessage 创建一个新的邮件消息构建器
+func NewMessage() *MessageBuilder {
+	return &MessageBuilder{msg: &Message{}}
+}
+
+// From 设置发件人
+func (b *MessageBuilder) From(address string) *MessageBuilder {
+	addr, err := mail.ParseAddress(address)
+	if err != nil {
+		b.errs = append(b.errs, &ValidationError{Field: "from", Message: "invalid address " + strconv.Quote(address), Err: err})
+		return b
+	}
+
+	b.msg.From = *addr
+	return b
+}
+
+// To 追加一个或多个收件人
+func (b *MessageBuilder) To(addresses ...string) *MessageBuilder {
+	b.msg.To = append(b.msg.To, b.parseAddresses("to", addresses)...)
+	return b
+}
+
+// Cc 追加一个或多个抄送收件人
+func (b *MessageBuilder) Cc(addresses ...string) *MessageBuilder {
+	b.msg.Cc = append(b.msg.Cc, b.parseAddresses("cc", addresses)...)
+	return b
+}
+
+// Bcc 追加一个或多个密送收件人
+func (b *MessageBuilder) Bcc(addresses ...string) *MessageBuilder {
+	b.msg.Bcc = append(b.msg.Bcc, b.parseAddresses("bcc", addresses)...)
+	return b
+}
+
+// ReplyTo 追加一个或多个回复地址
+func (b *MessageBuilder) ReplyTo(addresses ...string) *MessageBuilder {
+	b.msg.ReplyTo = append(b.msg.ReplyTo, b.parseAddresses("replyTo", addresses)...)
+	return b
+}
+
+// Sender 设置实际发送者（仅在与 From 不同时需要）
+func (b *MessageBuilder) Sender(address string) *MessageBuilder {
+	addr, err := mail.ParseAddress(address)
+	if err != nil {
+		b.errs = append(b.errs, &ValidationError{Field: "sender", Message: "invalid address " + strconv.Quote(address), Err: err})
+		return b
+	}
+
+	b.msg.Sender = *addr
+	return b
+}
+
+// ReadReceiptTo 请求阅读回执，回执发送到指定的地址
+func (b *MessageBuilder) ReadReceiptTo(addresses ...string) *MessageBuilder {
+	b.msg.ReadReceiptTo = append(b.msg.ReadReceiptTo, b.parseAddresses("readReceiptTo", addresses)...)
+	return b
+}
+
+// DeliveryNotification 请求投递状态通知（送达回执），notify 为空时在投递成功和失败时通知
+func (b *MessageBuilder) DeliveryNotification(notify ...DSNNotify) *MessageBuilder {
+	b.msg.DeliveryNotification = &DeliveryNotification{Notify: notify}
+	return b
+}
+
+// InReplyTo 设置被回复邮件的 Message-ID
+func (b *MessageBuilder) InReplyTo(messageID string) *MessageBuilder {
+	b.msg.InReplyTo = messageID
+	return b
+}
+
+// References 追加会话中此前邮件的 Message-ID
+func (b *MessageBuilder) References(messageIDs ...string) *MessageBuilder {
+	b.msg.References = append(b.msg.References, messageIDs...)
+	return b
+}
+
+// Subject 设置邮件主题
+func (b *MessageBuilder) Subject(subject string) *MessageBuilder {
+	b.msg.Subject = subject
+	return b
+}
+
+// Priority 设置邮件优先级
+func (b *MessageBuilder) Priority(p Priority) *MessageBuilder {
+	b.msg.Priority = p
+	return b
+}
+
+// Charset 设置邮件头和正文使用的字符集（如 "ISO-2022-JP"、"GB18030"），默认为 UTF-8
+func (b *MessageBuilder) Charset(name string) *MessageBuilder {
+	b.msg.Charset = name
+	return b
+}
+
+// HTML 设置 HTML 正文
+func (b *MessageBuilder) HTML(html string) *MessageBuilder {
+	b.msg.HTML = html
+	return b
+}
+
+// Text 设置纯文本正文
+func (b *MessageBuilder) Text(text string) *MessageBuilder {
+	b.msg.Text = text
+	return b
+}
+
+// Attach 添加一个普通附件（MIME 类型根据文件名和内容自动推断）
+func (b *MessageBuilder) Attach(name string, r io.Reader) *MessageBuilder {
+	return b.Attachment(Attachment{Filename: name, Reader: r})
+}
+
+// Embed 添加一个内联附件，可以在 HTML 中通过 "cid:name" 引用
+func (b *MessageBuilder) Embed(name string, r io.Reader) *MessageBuilder {
+	return b.Attachment(Attachment{Filename: name, Disposition: DispositionInline, Reader: r})
+}
+
+// AttachFile 添加一个本地文件作为普通附件，文件在发送时才打开并在发送后关闭
+func (b *MessageBuilder) AttachFile(path string) *MessageBuilder {
+	b.msg.AttachFile(path)
+	return b
+}
+
+// AttachFS 从文件系统（如 embed.FS）中添加一个普通附件
+func (b *MessageBuilder) AttachFS(fsys fs.FS, name string) *MessageBuilder {
+	b.msg.AttachFS(fsys, name)
+	return b
+}
+
+// EmbedFile 添加一个本地文件作为内联附件，可以在 HTML 中通过 "cid:文件名" 引用
+func (b *MessageBuilder) EmbedFile(path string) *MessageBuilder {
+	b.msg.EmbedFile(path)
+	return b
+}
+
+// EmbedFS 从文件系统（如 embed.FS）中添加一个内联附件，可以在 HTML 中通过 "cid:文件名" 引用
+func (b *MessageBuilder) EmbedFS(fsys fs.FS, name string) *MessageBuilder {
+	b.msg.EmbedFS(fsys, name)
+	return b
+}
+
+// EmbedImages 将 HTML 中引用的 data: URI 和本地图片转换为内联附件，
+// 需要在设置 HTML 之后调用，详见 Message.EmbedImages
+func (b *MessageBuilder) EmbedImages(opts EmbedImagesOptions) *MessageBuilder {
+	if err := b.msg.EmbedImages(opts); err != nil {
+		b.errs = append(b.errs, &ValidationError{Field: "html", Message: err.Error(), Err: err})
+	}
+	return b
+}
+
+// Attachment 添加一个带有完整元数据的附件，附件按添加顺序发送
+func (b *MessageBuilder) Attachment(a Attachment) *MessageBuilder {
+	b.msg.Files = append(b.msg.Files, a)
+	return b
+}
+
+// Header 设置一个自定义邮件头
+func (b *MessageBuilder) Header(name, value string) *MessageBuilder {
+	if b.msg.Headers == nil {
+		b.msg.Headers = map[string]string{}
+	}
+	b.msg.Headers[name] = value
+	return b
+}
+
+// Build 校验并返回构建好的邮件消息
+//
+// 返回:
+//   - *Message: 构建的邮件消息（即使校验失败也会返回，便于调试）
+//   - error: 地址解析或 Message.Validate 发现问题时返回 ValidationErrors
+func (b *MessageBuilder) Build() (*Message, error) {
+	errs := append(ValidationErrors{}, b.errs...)
+
+	if err := b.msg.Validate(); err != nil {
+		if verrs, ok := err.(ValidationErrors); ok {
+			errs = append(errs, verrs...)
+		}
+	}
+
+	if len(errs) > 0 {
+		return b.msg, errs
+	}
+
+	return b.msg, nil
+}
+
+// parseAddresses 解析地址列表字符串，失败的记录到构建器的错误中
+func (b *MessageBuilder) parseAddresses(field string, addresses []string) []mail.Address {
+	var result []mail.Address
+
+	for _, address := range addresses {
+		list, err := mail.ParseAddressList(address)
+		if err != nil {
+			b.errs = append(b.errs, &ValidationError{Field: field, Message: "invalid address " + strconv.Quote(address), Err: err})
+			continue
+		}
+		for _, addr := range list {
+			result = append(result, *addr)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,204 @@
+package gomailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// SendResult 描述了批量发送中单封邮件的发送结果
+type SendResult struct {
+	// Index 邮件在传入切片中的下标
+	Index int
+
+	// Message 对应的邮件消息
+	Message *Message
+
+	// Err 发送失败时的错误，成功为 nil
+	Err error
+
+	// Duration 本次发送耗时（未实际发送时为 0）
+	Duration time.Duration
+}
+
+// SendManyResult 汇总了一次批量发送的结果
+type SendManyResult struct {
+	// Results 每封邮件的发送结果，与传入的邮件一一对应（按下标排列）
+	Results []SendResult
+
+	// Sent 发送成功的邮件数
+	Sent int
+
+	// Failed 发送失败（包括因 context 结束而未发送）的邮件数
+	Failed int
+
+	// Duration 整个批量发送的耗时
+	Duration time.Duration
+}
+
+// Err 将所有失败合并为一个错误返回，全部成功时返回 nil
+//
+// 返回的错误可以通过 errors.Is/errors.As 检查其中包含的单个错误
+func (r *SendManyResult) Err() error {
+	if r == nil || r.Failed == 0 {
+		return nil
+	}
+
+	errs := make([]error, 0, r.Failed)
+	for _, res := range r.Results {
+		if res.Err != nil {
+			errs = append(errs, fmt.Errorf("message %d: %w", res.Index, res.Err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// BulkSender 使用固定数量的并发 worker 通过同一个 Mailer 批量发送邮件
+//
+// 单封邮件发送失败不会中断整个批次，所有结果都会记录在返回的 SendManyResult 中
+//
+// Mailer 实现了 SessionMailer（如 SMTPClient，以及包装了它的 RateLimitedMailer、
+// CircuitBreakerMailer）时，每个 worker 使用一个会话，连续发送的邮件复用同一个 SMTP 连接
+//
+// 示例:
+//
+//	sender := &BulkSender{Mailer: client, Concurrency: 8}
+//	result := sender.SendMany(ctx, messages)
+//	log.Printf("成功 %d 封，失败 %d 封", result.Sent, result.Failed)
+type BulkSender struct {
+	// Mailer 实际执行发送的邮件客户端，需要支持并发调用
+	Mailer Mailer
+
+	// DisableSessions 为 true 时不使用 SessionMailer 的会话，每封邮件单独调用 Mailer
+	DisableSessions bool
+
+	// Concurrency 并发发送的 worker 数量
+	// 如果未明确设置，默认为 4
+	Concurrency int
+
+	// OnResult 可选的回调，每封邮件发送完成后调用一次（串行调用，可用于进度统计）
+	OnResult func(result SendResult)
+}
+
+// SendMany 是使用默认配置的 BulkSender 批量发送邮件的快捷方式
+//
+// 参数:
+//   - ctx: 控制整个批次的 context，结束后尚未发送的邮件会记录 ctx.Err()
+//   - mailer: 执行发送的邮件客户端
+//   - messages: 要发送的邮件列表
+//   - concurrency: 并发 worker 数量，小于等于 0 时使用默认值
+//
+// 返回:
+//   - *SendManyResult: 每封邮件的发送结果及汇总
+func SendMany(ctx context.Context, mailer Mailer, messages []*Message, concurrency int) *SendManyResult {
+	sender := &BulkSender{Mailer: mailer, Concurrency: concurrency}
+	return sender.SendMany(ctx, messages)
+}
+
+// SendMany 并发发送所有邮件并返回汇总结果
+//
+// 如果 Mailer 实现了 ContextMailer，ctx 会传递给每一次发送
+//
+// 参数:
+//   - ctx: 控制整个批次的 context，结束后尚未发送的邮件会记录 ctx.Err()
+//   - messages: 要发送的邮件列表
+//
+// 返回:
+//   - *SendManyResult: 每封邮件的发送结果及汇总
+func (b *BulkSender) SendMany(ctx context.Context, messages []*Message) *SendManyResult {
+	start := time.Now()
+
+	result := &SendManyResult{
+		Results: make([]SendResult, len(messages)),
+	}
+
+	workers := b.Concurrency
+	if workers <= 0 {
+		workers = 4
+	}
+	if workers > len(messages) {
+		workers = len(messages)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	jobs := make(chan int)
+
+	// 记录单封邮件的结果并串行调用 OnResult
+	report := func(res SendResult) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		result.Results[res.Index] = res
+		if res.Err != nil {
+			result.Failed++
+		} else {
+			result.Sent++
+		}
+
+		if b.OnResult != nil {
+			b.OnResult(res)
+		}
+	}
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// 每个 worker 使用自己的会话，会话在 worker 结束时关闭
+			mailer := b.Mailer
+			if mailer != nil && !b.DisableSessions {
+				session := newSession(mailer)
+				defer session.Close()
+				mailer = session
+			}
+
+			for index := range jobs {
+				report(b.sendOne(ctx, mailer, index, messages[index]))
+			}
+		}()
+	}
+
+	// 分发任务，ctx 结束后不再分发，剩余邮件直接记录为失败
+	next := 0
+DISPATCH:
+	for ; next < len(messages); next++ {
+		select {
+		case jobs <- next:
+		case <-ctx.Done():
+			break DISPATCH
+		}
+	}
+	close(jobs)
+
+	wg.Wait()
+
+	for ; next < len(messages); next++ {
+		report(SendResult{Index: next, Message: messages[next], Err: ctx.Err()})
+	}
+
+	result.Duration = time.Since(start)
+
+	return result
+}
+
+// sendOne 通过 mailer（Mailer 或 worker 的会话）发送单封邮件并生成对应的结果
+func (b *BulkSender) sendOne(ctx context.Context, mailer Mailer, index int, m *Message) SendResult {
+	res := SendResult{Index: index, Message: m}
+
+	if mailer == nil {
+		res.Err = errors.New("bulk sender has no underlying mailer")
+		return res
+	}
+
+	start := time.Now()
+	res.Err = sendWithContext(ctx, mailer, m)
+	res.Duration = time.Since(start)
+
+	return res
+}
@@ -0,0 +1,88 @@
+package gomailer
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"testing"
+)
+
+func testMessages(n int) []*Message {
+	messages := make([]*Message, n)
+	for i := range messages {
+		messages[i] = &Message{
+			From:    mail.Address{Address: "sender@example.com"},
+			To:      []mail.Address{{Address: fmt.Sprintf("user%d@example.com", i)}},
+			Subject: fmt.Sprintf("message %d", i),
+			Text:    "hello",
+		}
+	}
+	return messages
+}
+
+func TestSendManyReusesSMTPConnections(t *testing.T) {
+	tests := []struct {
+		name   string
+		mailer func(c *SMTPClient) Mailer
+	}{
+		{"client", func(c *SMTPClient) Mailer { return c }},
+		{"rate limited", func(c *SMTPClient) Mailer { return &RateLimitedMailer{Mailer: c} }},
+		{"circuit breaker", func(c *SMTPClient) Mailer { return &CircuitBreakerMailer{Mailer: c} }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeSMTP(t)
+
+			result := SendMany(context.Background(), tt.mailer(f.client()), testMessages(20), 3)
+			if err := result.Err(); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := len(f.transactions()); got != 20 {
+				t.Errorf("transactions = %d, want 20", got)
+			}
+			if got := f.connections(); got > 3 {
+				t.Errorf("connections = %d, want at most one per worker (3)", got)
+			}
+		})
+	}
+}
+
+func TestSendManyDisableSessions(t *testing.T) {
+	f := newFakeSMTP(t)
+
+	sender := &BulkSender{Mailer: f.client(), Concurrency: 2, DisableSessions: true}
+	if err := sender.SendMany(context.Background(), testMessages(5)).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := f.connections(); got != 5 {
+		t.Errorf("connections = %d, want 5", got)
+	}
+}
+
+func TestSMTPSessionReconnectsAfterConnectionLoss(t *testing.T) {
+	f := newFakeSMTP(t)
+	session := f.client().NewSession()
+	defer session.Close()
+
+	messages := testMessages(2)
+	if err := session.Send(messages[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	// 模拟服务器关闭空闲连接
+	session.(*SMTPSession).conn.Text.Close()
+
+	if err := session.Send(messages[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := len(f.transactions()); got != 2 {
+		t.Errorf("transactions = %d, want 2", got)
+	}
+	if got := f.connections(); got != 2 {
+		t.Errorf("connections = %d, want 2", got)
+	}
+}
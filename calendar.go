@@ -0,0 +1,511 @@
+package gomailer
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// CalendarMethod 是 iCalendar 的 METHOD（RFC 5546），决定收件人客户端如何处理日程
+type CalendarMethod string
+
+const (
+	// CalendarRequest 会议邀请或更新，客户端会显示接受/拒绝按钮
+	CalendarRequest CalendarMethod = "REQUEST"
+
+	// CalendarCancel 取消会议，UID 必须与原邀请相同，Sequence 应大于原邀请
+	CalendarCancel CalendarMethod = "CANCEL"
+)
+
+// CalendarAttendee 会议参与者
+type CalendarAttendee struct {
+	// Address 参与者地址和姓名
+	Address mail.Address `json:"address"`
+
+	// Optional 是否为可选参与者（默认为必需参与者）
+	Optional bool `json:"optional,omitempty"`
+}
+
+// CalendarEvent 描述一个会议邀请，设置到 Message.Calendar 后会作为 text/calendar
+// 替代部分与 HTML/纯文本正文一起发送，Outlook、Gmail 等客户端会将其显示为可以接受或拒绝的邀请
+//
+// Start 和 End 使用其自身的时区：带有 IANA 名称的时区（如 time.LoadLocation("Asia/Shanghai")）
+// 会以 TZID 和 VTIMEZONE 的形式写入，UTC 和 time.Local 会转换为 UTC 时间
+type CalendarEvent struct {
+	// Method REQUEST（默认）或 CANCEL
+	Method CalendarMethod `json:"method,omitempty"`
+
+	// UID 日程的唯一标识，更新或取消同一个会议时必须保持不变
+	UID string `json:"uid"`
+
+	// Sequence 修订序号，每次更新或取消时递增
+	Sequence int `json:"sequence,omitempty"`
+
+	// Organizer 组织者，通常与 Message.From 相同
+	Organizer mail.Address `json:"organizer"`
+
+	// Attendees 参与者列表
+	Attendees []CalendarAttendee `json:"attendees"`
+
+	// Summary 会议标题，为空时使用邮件主题
+	Summary string `json:"summary,omitempty"`
+
+	// Description 会议说明
+	Description string `json:"description,omitempty"`
+
+	// Location 会议地点
+	Location string `json:"location,omitempty"`
+
+	// Start 开始时间
+	Start time.Time `json:"start"`
+
+	// End 结束时间
+	End time.Time `json:"end"`
+}
+
+// method 返回日程的 METHOD，未设置时为 REQUEST
+func (e *CalendarEvent) method() CalendarMethod {
+	if e.Method == "" {
+		return CalendarRequest
+	}
+	return CalendarMethod(strings.ToUpper(string(e.Method)))
+}
+
+// validate 检查日程，问题记录到 errs
+func (e *CalendarEvent) validate(errs *ValidationErrors, field string) {
+	switch e.method() {
+	case CalendarRequest, CalendarCancel:
+	default:
+		errs.add(field+".method", "unsupported method %q", e.Method)
+	}
+
+	if strings.TrimSpace(e.UID) == "" {
+		errs.add(field+".uid", "uid is required")
+	} else if err := checkHeaderValue("UID", e.UID); err != nil {
+		errs.addErr(field+".uid", err)
+	}
+
+	if e.Sequence < 0 {
+		errs.add(field+".sequence", "sequence must not be negative")
+	}
+
+	if e.Organizer.Address == "" {
+		errs.add(field+".organizer", "organizer is required")
+	} else {
+		validateAddress(errs, field+".organizer", e.Organizer)
+	}
+
+	if len(e.Attendees) == 0 {
+		errs.add(field+".attendees", "at least one attendee is required")
+	}
+	for i, attendee := range e.Attendees {
+		validateAddress(errs, fmt.Sprintf("%s.attendees[%d]", field, i), attendee.Address)
+	}
+
+	switch {
+	case e.Start.IsZero():
+		errs.add(field+".start", "start time is required")
+	case e.End.IsZero():
+		errs.add(field+".end", "end time is required")
+	case !e.End.After(e.Start):
+		errs.add(field+".end", "end time must be after start time")
+	}
+}
+
+// calendarPart 创建 text/calendar 实体
+func calendarPart(e *CalendarEvent, summary string) *mimePart {
+	contentType := mime.FormatMediaType("text/calendar", map[string]string{
+		"charset": "UTF-8",
+		"method":  string(e.method()),
+	})
+
+	return encodedTextPart(contentType, e.ics(summary, time.Now()))
+}
+
+// ics 将日程渲染为 iCalendar（RFC 5545）文本
+func (e *CalendarEvent) ics(summary string, now time.Time) string {
+	if e.Summary != "" {
+		summary = e.Summary
+	}
+
+	var b strings.Builder
+	line := func(s string) {
+		b.WriteString(foldICSLine(s))
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("PRODID:-//gomailer//gomailer//EN")
+	line("VERSION:2.0")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:" + string(e.method()))
+
+	for _, tz := range icsTimeZones(e.Start, e.End) {
+		b.WriteString(tz)
+	}
+
+	line("BEGIN:VEVENT")
+	line("UID:" + escapeICSText(e.UID))
+	line("SEQUENCE:" + strconv.Itoa(e.Sequence))
+	line("DTSTAMP:" + now.UTC().Format("20060102T150405Z"))
+	line(icsDateTime("DTSTART", e.Start))
+	line(icsDateTime("DTEND", e.End))
+	line("SUMMARY:" + escapeICSText(summary))
+	if e.Description != "" {
+		line("DESCRIPTION:" + escapeICSText(e.Description))
+	}
+	if e.Location != "" {
+		line("LOCATION:" + escapeICSText(e.Location))
+	}
+
+	line("ORGANIZER" + icsCommonName(e.Organizer.Name) + ":mailto:" + e.Organizer.Address)
+	for _, attendee := range e.Attendees {
+		role := "REQ-PARTICIPANT"
+		if attendee.Optional {
+			role = "OPT-PARTICIPANT"
+		}
+		line("ATTENDEE" + icsCommonName(attendee.Address.Name) +
+			";ROLE=" + role + ";PARTSTAT=NEEDS-ACTION;RSVP=TRUE:mailto:" + attendee.Address.Address)
+	}
+
+	if e.method() == CalendarCancel {
+		line("STATUS:CANCELLED")
+	} else {
+		line("STATUS:CONFIRMED")
+	}
+	line("TRANSP:OPAQUE")
+	line("END:VEVENT")
+	line("END:VCALENDAR")
+
+	return b.String()
+}
+
+// icsTZID 返回时间所在时区的 TZID，UTC、本地时区等没有 IANA 名称的时区返回空字符串
+func icsTZID(t time.Time) string {
+	name := t.Location().String()
+	if name == "" || name == "UTC" || name == "Local" || !strings.Contains(name, "/") {
+		return ""
+	}
+	return name
+}
+
+// icsDateTime 格式化 DTSTART/DTEND 属性：有 TZID 时使用当地时间，否则使用 UTC
+func icsDateTime(name string, t time.Time) string {
+	if tzid := icsTZID(t); tzid != "" {
+		return name + ";TZID=" + tzid + ":" + t.Format("20060102T150405")
+	}
+	return name + ":" + t.UTC().Format("20060102T150405Z")
+}
+
+// icsTimeZones 为开始和结束时间所在的时区生成 VTIMEZONE 组件
+//
+// 组件只包含事件所在年份及前一年内的实际时区转换（不使用 RRULE），足以让客户端正确显示本次会议
+func icsTimeZones(times ...time.Time) []string {
+	var result []string
+	seen := map[string]bool{}
+
+	for _, t := range times {
+		tzid := icsTZID(t)
+		if tzid == "" || seen[tzid] {
+			continue
+		}
+		seen[tzid] = true
+
+		loc := t.Location()
+		// 从前一年开始查找，使事件之前最近的一次转换也包含在内
+		from := time.Date(times[0].In(loc).Year()-1, 1, 1, 0, 0, 0, 0, loc)
+		to := time.Date(times[len(times)-1].In(loc).Year()+1, 1, 1, 0, 0, 0, 0, loc)
+
+		var b strings.Builder
+		b.WriteString(foldICSLine("BEGIN:VTIMEZONE"))
+		b.WriteString(foldICSLine("TZID:" + tzid))
+
+		transitions := zoneTransitions(from, to)
+		if len(transitions) == 0 {
+			_, offset := from.Zone()
+			writeICSZone(&b, "STANDARD", time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC), offset, offset)
+		}
+		for _, tr := range transitions {
+			kind := "STANDARD"
+			if tr.after.IsDST() {
+				kind = "DAYLIGHT"
+			}
+			_, before := tr.before.Zone()
+			_, after := tr.after.Zone()
+			// DTSTART 为转换发生时按旧偏移量计算的当地时间
+			local := tr.after.UTC().Add(time.Duration(before) * time.Second)
+			writeICSZone(&b, kind, local, before, after)
+		}
+
+		b.WriteString(foldICSLine("END:VTIMEZONE"))
+		result = append(result, b.String())
+	}
+
+	return result
+}
+
+// writeICSZone 写入 VTIMEZONE 中的一个 STANDARD/DAYLIGHT 子组件
+func writeICSZone(b *strings.Builder, kind string, start time.Time, from, to int) {
+	b.WriteString(foldICSLine("BEGIN:" + kind))
+	b.WriteString(foldICSLine("DTSTART:" + start.Format("20060102T150405")))
+	b.WriteString(foldICSLine("TZOFFSETFROM:" + icsOffset(from)))
+	b.WriteString(foldICSLine("TZOFFSETTO:" + icsOffset(to)))
+	b.WriteString(foldICSLine("END:" + kind))
+}
+
+// zoneTransition 是一次时区偏移量的变化
+type zoneTransition struct {
+	before time.Time // 变化前的最后一刻
+	after  time.Time // 变化后的第一刻
+}
+
+// zoneTransitions 找出 [from, to) 之间的全部时区偏移量变化（精确到秒）
+func zoneTransitions(from, to time.Time) []zoneTransition {
+	var result []zoneTransition
+
+	for t := from; t.Before(to); {
+		next := t.Add(24 * time.Hour)
+		_, offset := t.Zone()
+		if _, nextOffset := next.Zone(); nextOffset != offset {
+			// 二分查找发生变化的时刻
+			lo, hi := t, next
+			for hi.Sub(lo) > time.Second {
+				mid := lo.Add(hi.Sub(lo) / 2)
+				if _, o := mid.Zone(); o == offset {
+					lo = mid
+				} else {
+					hi = mid
+				}
+			}
+			result = append(result, zoneTransition{before: lo, after: hi})
+		}
+		t = next
+	}
+
+	return result
+}
+
+// icsOffset 将 UTC 偏移秒数格式化为 "+0800" 形式
+func icsOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds/60%60)
+}
+
+// icsCommonName 返回 ";CN=..." 参数，姓名为空时返回空字符串
+func icsCommonName(name string) string {
+	name = strings.NewReplacer(`"`, "", "\r", "", "\n", "").Replace(name)
+	if name == "" {
+		return ""
+	}
+	return `;CN="` + name + `"`
+}
+
+// escapeICSText 按 RFC 5545 3.3.11 转义 TEXT 类型的值
+func escapeICSText(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	).Replace(s)
+}
+
+// foldICSLine 按 RFC 5545 3.1 将内容行折叠为每行不超过 75 个字节，返回以 CRLF 结尾的文本
+//
+// 折行不会拆分 UTF-8 多字节字符，续行以一个空格开头
+func foldICSLine(s string) string {
+	const limit = 75
+
+	var b strings.Builder
+	for first := true; ; first = false {
+		max := limit
+		if !first {
+			max-- // 续行开头的空格也计入长度
+			b.WriteString(" ")
+		}
+
+		if len(s) <= max {
+			b.WriteString(s)
+			b.WriteString("\r\n")
+			return b.String()
+		}
+
+		cut := max
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n")
+		s = s[cut:]
+	}
+}
+
+// parseICS 将 iCalendar 文本解析为日程，用于 ParseMessage 还原会议邀请
+//
+// 只读取第一个 VEVENT 中 CalendarEvent 对应的属性，VTIMEZONE、VALARM 等其它组件和属性会被忽略。
+// 带 TZID 的时间使用同名的 IANA 时区，无法加载时返回错误；解析结果无法通过校验时同样返回错误
+//
+// 参数:
+//   - text: iCalendar 文本
+//   - method: Content-Type 的 method 参数，VCALENDAR 中没有 METHOD 属性时使用
+func parseICS(text string, method string) (*CalendarEvent, error) {
+	// 展开折行（RFC 5545 3.1）
+	text = strings.NewReplacer("\r\n ", "", "\r\n\t", "", "\n ", "", "\n\t", "").Replace(text)
+
+	e := &CalendarEvent{Method: CalendarMethod(strings.ToUpper(method))}
+	var components []string
+	found := false
+
+lines:
+	for _, line := range strings.Split(text, "\n") {
+		name, params, value, ok := parseICSLine(strings.TrimRight(line, "\r"))
+		if !ok {
+			continue
+		}
+
+		switch name {
+		case "BEGIN":
+			components = append(components, strings.ToUpper(value))
+			if len(components) == 2 && components[1] == "VEVENT" {
+				found = true
+			}
+			continue
+		case "END":
+			if len(components) == 2 && components[1] == "VEVENT" {
+				// 只还原第一个 VEVENT
+				break lines
+			}
+			if len(components) > 0 {
+				components = components[:len(components)-1]
+			}
+			continue
+		}
+
+		if len(components) == 1 && components[0] == "VCALENDAR" && name == "METHOD" {
+			e.Method = CalendarMethod(strings.ToUpper(value))
+			continue
+		}
+
+		if len(components) != 2 || components[0] != "VCALENDAR" || components[1] != "VEVENT" {
+			continue
+		}
+
+		var err error
+		switch name {
+		case "UID":
+			e.UID = unescapeICSText(value)
+		case "SEQUENCE":
+			e.Sequence, err = strconv.Atoi(value)
+		case "DTSTART":
+			e.Start, err = parseICSDateTime(params, value)
+		case "DTEND":
+			e.End, err = parseICSDateTime(params, value)
+		case "SUMMARY":
+			e.Summary = unescapeICSText(value)
+		case "DESCRIPTION":
+			e.Description = unescapeICSText(value)
+		case "LOCATION":
+			e.Location = unescapeICSText(value)
+		case "ORGANIZER":
+			e.Organizer = parseICSAddress(params, value)
+		case "ATTENDEE":
+			e.Attendees = append(e.Attendees, CalendarAttendee{
+				Address:  parseICSAddress(params, value),
+				Optional: strings.EqualFold(params["ROLE"], "OPT-PARTICIPANT"),
+			})
+		}
+		if err != nil {
+			return nil, fmt.Errorf("calendar %s: %w", strings.ToLower(name), err)
+		}
+	}
+
+	if !found {
+		return nil, errors.New("calendar has no VEVENT")
+	}
+
+	var errs ValidationErrors
+	e.validate(&errs, "calendar")
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return e, nil
+}
+
+// parseICSLine 将展开后的内容行拆分为属性名（大写）、参数和值
+func parseICSLine(line string) (name string, params map[string]string, value string, ok bool) {
+	i := strings.IndexAny(line, ";:")
+	if i < 0 {
+		return "", nil, "", false
+	}
+
+	name = strings.ToUpper(line[:i])
+	params = map[string]string{}
+
+	for line[i] == ';' {
+		line = line[i+1:]
+
+		// 参数值可以用双引号包裹，其中的 ";" 和 ":" 不作为分隔符
+		quoted := false
+		i = strings.IndexFunc(line, func(r rune) bool {
+			if r == '"' {
+				quoted = !quoted
+			}
+			return !quoted && (r == ';' || r == ':')
+		})
+		if i < 0 {
+			return "", nil, "", false
+		}
+
+		key, val, _ := strings.Cut(line[:i], "=")
+		params[strings.ToUpper(key)] = strings.Trim(val, `"`)
+	}
+
+	return name, params, line[i+1:], true
+}
+
+// parseICSDateTime 解析 DTSTART/DTEND 的值：UTC 时间、带 TZID 的当地时间或浮动时间（按 time.Local 处理）
+func parseICSDateTime(params map[string]string, value string) (time.Time, error) {
+	if tzid := params["TZID"]; tzid != "" {
+		loc, err := time.LoadLocation(tzid)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.ParseInLocation("20060102T150405", value, loc)
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		return time.Parse("20060102T150405Z", value)
+	}
+	return time.ParseInLocation("20060102T150405", value, time.Local)
+}
+
+// parseICSAddress 解析 ORGANIZER/ATTENDEE 的 "mailto:" 地址和 CN 参数
+func parseICSAddress(params map[string]string, value string) mail.Address {
+	if len(value) >= len("mailto:") && strings.EqualFold(value[:len("mailto:")], "mailto:") {
+		value = value[len("mailto:"):]
+	}
+	return mail.Address{Name: params["CN"], Address: value}
+}
+
+// unescapeICSText 还原 escapeICSText 转义的 TEXT 值
+func unescapeICSText(s string) string {
+	return strings.NewReplacer(
+		`\\`, `\`,
+		`\;`, ";",
+		`\,`, ",",
+		`\n`, "\n",
+		`\N`, "\n",
+	).Replace(s)
+}
@@ -0,0 +1,257 @@
+package gomailer
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/mail"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/ianaindex"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// ErrUnrepresentableCharacter 表示文本中包含目标字符集无法表示的字符
+//
+// 转码失败时返回的 *CharsetError 满足 errors.Is(err, ErrUnrepresentableCharacter)
+var ErrUnrepresentableCharacter = errors.New("character cannot be represented in charset")
+
+// CharsetError 描述了一个无法用目标字符集表示的字符
+type CharsetError struct {
+	// Charset 目标字符集
+	Charset string
+
+	// Rune 无法表示的字符
+	Rune rune
+
+	// Offset 字符在原文本中的字节偏移
+	Offset int
+}
+
+// Error 实现 error 接口
+func (e *CharsetError) Error() string {
+	return fmt.Sprintf("character %q (%U) at offset %d cannot be represented in charset %s", e.Rune, e.Rune, e.Offset, e.Charset)
+}
+
+// Is 使 errors.Is(err, ErrUnrepresentableCharacter) 返回 true
+func (e *CharsetError) Is(target error) bool {
+	return target == ErrUnrepresentableCharacter
+}
+
+// charset 是一个用于发送的字符集
+type charset struct {
+	// name 写入 Content-Type 和 RFC 2047 编码字的字符集名称
+	name string
+
+	// enc 字符集编码，UTF-8 时为 nil（不需要转码）
+	enc encoding.Encoding
+}
+
+// lookupCharset 根据名称（如 "ISO-2022-JP"、"GB18030"，不区分大小写）查找字符集
+//
+// 名称为空或为 UTF-8 时返回 UTF-8。UTF-16 等与 ASCII 不兼容的字符集不能用于邮件正文和邮件头
+//
+// 名称先按 IANA 注册的 MIME 名称查找，找不到时按 WHATWG Encoding 标准的别名查找：
+// 例如 "GB2312" 没有对应的实现，按 WHATWG 的规定使用其超集 GBK，并以 "GBK" 声明
+func lookupCharset(name string) (*charset, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return &charset{name: "UTF-8"}, nil
+	}
+
+	enc, err := ianaindex.MIME.Encoding(name)
+	if err != nil || enc == nil {
+		if enc, err = htmlindex.Get(name); err != nil {
+			return nil, fmt.Errorf("unsupported charset %q", name)
+		}
+	}
+
+	canonical, err := ianaindex.MIME.Name(enc)
+	if err != nil {
+		canonical = strings.ToUpper(name)
+	}
+
+	if enc == unicode.UTF8 {
+		return &charset{name: "UTF-8"}, nil
+	}
+
+	// 邮件要求字符集与 ASCII 兼容：ASCII 字符、CR 和 LF 必须保持原样
+	if out, err := enc.NewEncoder().String("A=\r\n"); err != nil || out != "A=\r\n" {
+		return nil, fmt.Errorf("charset %q is not ASCII compatible", name)
+	}
+
+	return &charset{name: canonical, enc: enc}, nil
+}
+
+// utf8 返回字符集是否为 UTF-8（不需要转码）
+func (c *charset) utf8() bool {
+	return c.enc == nil
+}
+
+// encode 将 UTF-8 文本转码为目标字符集，无法表示的字符返回 *CharsetError
+func (c *charset) encode(s string) (string, error) {
+	if c.utf8() {
+		return s, nil
+	}
+
+	out, err := c.enc.NewEncoder().String(s)
+	if err == nil {
+		return out, nil
+	}
+
+	// 逐个字符定位无法表示的字符，给出准确的错误
+	encoder := c.enc.NewEncoder()
+	for i, r := range s {
+		encoder.Reset()
+		if _, rerr := encoder.String(string(r)); rerr != nil {
+			return "", &CharsetError{Charset: c.name, Rune: r, Offset: i}
+		}
+	}
+
+	return "", fmt.Errorf("charset %s: %w", c.name, err)
+}
+
+// headerText 将非结构化的邮件头文本编码为 RFC 2047 编码字
+//
+// UTF-8 与 encodeHeaderText 相同；其它字符集使用 B 编码，按字符拆分为多个独立转码的编码字，
+// 保证每个编码字都可以单独解码（ISO-2022-JP 等有状态的编码在每个编码字结束时回到 ASCII 状态）
+func (c *charset) headerText(value string) (string, error) {
+	if c.utf8() {
+		return encodeHeaderText(value), nil
+	}
+
+	if !needsEncodedWords(value) {
+		return value, nil
+	}
+
+	// 先整体转码，检查是否有无法表示的字符
+	if _, err := c.encode(value); err != nil {
+		return "", err
+	}
+
+	prefix := "=?" + c.name + "?B?"
+	// 编码字的最大长度为 75 个字符（RFC 2047 第 2 节）
+	maxEncoded := 75 - len(prefix) - len("?=")
+	maxRaw := maxEncoded / 4 * 3
+
+	var words []string
+	var chunk strings.Builder
+	var encoded string
+
+	flush := func() {
+		if chunk.Len() > 0 {
+			words = append(words, prefix+base64.StdEncoding.EncodeToString([]byte(encoded))+"?=")
+			chunk.Reset()
+			encoded = ""
+		}
+	}
+
+	for _, r := range value {
+		candidate, err := c.encode(chunk.String() + string(r))
+		if err != nil {
+			return "", err
+		}
+
+		if len(candidate) > maxRaw && chunk.Len() > 0 {
+			flush()
+			if candidate, err = c.encode(string(r)); err != nil {
+				return "", err
+			}
+		}
+
+		chunk.WriteRune(r)
+		encoded = candidate
+	}
+	flush()
+
+	return strings.Join(words, " "), nil
+}
+
+// address 将地址格式化为邮件头的值，姓名按字符集编码
+func (c *charset) address(addr mail.Address) (string, error) {
+	if c.utf8() || !needsEncodedWords(addr.Name) {
+		return formatAddress(addr), nil
+	}
+
+	name, err := c.headerText(addr.Name)
+	if err != nil {
+		return "", err
+	}
+
+	return name + " <" + addr.Address + ">", nil
+}
+
+// addressList 将地址列表格式化为邮件头的值
+func (c *charset) addressList(addrs []mail.Address) (string, error) {
+	parts := make([]string, len(addrs))
+	for i, addr := range addrs {
+		formatted, err := c.address(addr)
+		if err != nil {
+			return "", err
+		}
+		parts[i] = formatted
+	}
+	return strings.Join(parts, ", "), nil
+}
+
+// needsEncodedWords 检查文本是否需要编码为 RFC 2047 编码字
+func needsEncodedWords(s string) bool {
+	if strings.Contains(s, "=?") {
+		return true
+	}
+
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return true
+		}
+	}
+
+	return false
+}
+
+// -------------------------------------------------------------------
+// 解码
+// -------------------------------------------------------------------
+
+// decodeCharset 将 charset 编码的内容转换为 UTF-8
+//
+// 字符集名称按 WHATWG 编码标准解析（与浏览器一致，如 "gb2312" 按 GBK 解码），
+// 未知的字符集返回错误
+func decodeCharset(charsetName string, data []byte) (string, error) {
+	name := strings.ToLower(strings.TrimSpace(charsetName))
+	switch name {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		return string(data), nil
+	}
+
+	enc, err := htmlindex.Get(name)
+	if err != nil {
+		return "", fmt.Errorf("unsupported charset %q", charsetName)
+	}
+
+	out, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return "", err
+	}
+
+	return string(out), nil
+}
+
+// charsetReader 用于 mime.WordDecoder，支持解码非 UTF-8 的 RFC 2047 编码字
+func charsetReader(charsetName string, input io.Reader) (io.Reader, error) {
+	enc, err := htmlindex.Get(strings.ToLower(strings.TrimSpace(charsetName)))
+	if err != nil {
+		return nil, fmt.Errorf("unsupported charset %q", charsetName)
+	}
+	return enc.NewDecoder().Reader(input), nil
+}
+
+// newWordDecoder 创建支持常见字符集的 RFC 2047 解码器
+func newWordDecoder() *mime.WordDecoder {
+	return &mime.WordDecoder{CharsetReader: charsetReader}
+}
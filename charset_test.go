@@ -0,0 +1,138 @@
+package gomailer
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+func TestCharsetRoundTrip(t *testing.T) {
+	tests := []struct {
+		charset  string
+		wantName string
+		subject  string
+		name     string
+		text     string
+	}{
+		{"ISO-2022-JP", "ISO-2022-JP", "ご注文の確認", "山田太郎", "ご注文ありがとうございます。\n半角ｶﾅは含みません。"},
+		{"gb18030", "GB18030", "订单确认 €", "张三", "感谢您的订购。\n𠀀 生僻字也可以表示。"},
+		{"iso-8859-1", "ISO-8859-1", "Bestätigung", "Jürgen Müller", "Grüße aus Köln, ¡olé!"},
+		{"GB2312", "GBK", "订单确认", "张三", "感谢您的订购。"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.charset, func(t *testing.T) {
+			m := messageTo("to@example.com")
+			m.From.Name = tt.name
+			m.Subject = tt.subject
+			m.Text = tt.text
+			m.HTML = "<p>" + strings.ReplaceAll(tt.text, "\n", "<br>") + "</p>"
+			m.Charset = tt.charset
+
+			raw := string(renderBytes(t, m))
+
+			h := readHeader(t, raw)
+			if subject := h.Get("Subject"); !strings.HasPrefix(subject, "=?"+tt.wantName+"?B?") {
+				t.Errorf("Subject = %q, want %s encoded words", subject, tt.wantName)
+			}
+			if n := strings.Count(raw, "charset="+tt.wantName); n != 2 {
+				t.Errorf("charset=%s appears %d times, want text and HTML parts", tt.wantName, n)
+			}
+
+			parsed := reparse(t, m)
+			if parsed.Subject != tt.subject || parsed.From.Name != tt.name {
+				t.Errorf("Subject = %q, From = %q", parsed.Subject, parsed.From.Name)
+			}
+			// 正文按 CRLF 换行发送
+			if parsed.Text != strings.ReplaceAll(tt.text, "\n", "\r\n") {
+				t.Errorf("Text = %q, want %q", parsed.Text, tt.text)
+			}
+			if parsed.HTML != m.HTML {
+				t.Errorf("HTML = %q, want %q", parsed.HTML, m.HTML)
+			}
+		})
+	}
+}
+
+func TestCharsetPerPart(t *testing.T) {
+	m := messageTo("to@example.com")
+	m.Subject = "Grüße"
+	m.Text = "Grüße"
+	m.HTML = "<p>Grüße 😀</p>"
+	m.TextCharset = "ISO-8859-1"
+
+	raw := string(renderBytes(t, m))
+	if !strings.Contains(raw, "charset=ISO-8859-1") || !strings.Contains(raw, "charset=UTF-8") {
+		t.Errorf("want an ISO-8859-1 text part and a UTF-8 HTML part:\n%s", raw)
+	}
+
+	parsed := reparse(t, m)
+	if parsed.Text != m.Text || parsed.HTML != m.HTML || parsed.Subject != m.Subject {
+		t.Errorf("parsed = %q, %q, %q", parsed.Subject, parsed.Text, parsed.HTML)
+	}
+}
+
+func TestCharsetErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		modify        func(m *Message)
+		wantField     string
+		unrepresented bool
+	}{
+		{"unsupported charset", func(m *Message) { m.Charset = "x-klingon" }, "charset", false},
+		{"unsupported text charset", func(m *Message) { m.TextCharset = "x-klingon" }, "textCharset", false},
+		{"not ascii compatible", func(m *Message) { m.Charset = "UTF-16" }, "charset", false},
+		{"emoji in ISO-2022-JP subject", func(m *Message) {
+			m.Charset = "ISO-2022-JP"
+			m.Subject = "ご注文 😀"
+		}, "subject", true},
+		{"chinese in latin1 body", func(m *Message) {
+			m.Charset = "ISO-8859-1"
+			m.Text = "Preis: 中"
+		}, "text", true},
+		{"display name", func(m *Message) {
+			m.Charset = "ISO-8859-1"
+			m.To = []mail.Address{{Name: "张三", Address: "to@example.com"}}
+		}, "to[0]", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := messageTo("to@example.com")
+			tt.modify(m)
+
+			err := m.Validate()
+			if !errors.Is(err, ErrInvalidMessage) {
+				t.Fatalf("err = %v, want ErrInvalidMessage", err)
+			}
+
+			var errs ValidationErrors
+			if !errors.As(err, &errs) || errs[0].Field != tt.wantField {
+				t.Errorf("errors = %v, want field %q", err, tt.wantField)
+			}
+			if errors.Is(err, ErrUnrepresentableCharacter) != tt.unrepresented {
+				t.Errorf("errors.Is(err, ErrUnrepresentableCharacter) = %v", !tt.unrepresented)
+			}
+		})
+	}
+
+	// 错误指出无法表示的字符及其位置
+	m := messageTo("to@example.com")
+	m.Charset = "ISO-8859-1"
+	m.Text = "Preis: 中"
+
+	var csErr *CharsetError
+	if err := m.Validate(); !errors.As(err, &csErr) || csErr.Rune != '中' || csErr.Offset != 7 || csErr.Charset != "ISO-8859-1" {
+		t.Errorf("err = %v, want *CharsetError for '中' at offset 7", err)
+	}
+
+	// 发送时同样失败，不会连接服务器
+	f := newFakeSMTP(t)
+	if err := f.client().Send(m); !errors.Is(err, ErrUnrepresentableCharacter) {
+		t.Errorf("Send err = %v", err)
+	}
+	if n := f.connections(); n != 0 {
+		t.Errorf("connections = %d, want 0", n)
+	}
+}
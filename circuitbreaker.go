@@ -0,0 +1,338 @@
+package gomailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 确保 CircuitBreakerMailer 实现了 Mailer 和 ContextMailer 接口
+var (
+	_ Mailer        = (*CircuitBreakerMailer)(nil)
+	_ ContextMailer = (*CircuitBreakerMailer)(nil)
+)
+
+// ErrCircuitOpen 表示熔断器处于打开状态，发送请求被直接拒绝
+//
+// CircuitBreakerMailer 返回的 *CircuitOpenError 满足 errors.Is(err, ErrCircuitOpen)
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitOpenError 描述了一次被熔断器拒绝的发送
+type CircuitOpenError struct {
+	// State 拒绝时熔断器所处的状态
+	State CircuitState
+
+	// RetryAfter 距离熔断器进入半开状态的剩余时间
+	RetryAfter time.Duration
+}
+
+// Error 实现 error 接口
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker is %s, retry after %s", e.State, e.RetryAfter)
+}
+
+// Is 使 errors.Is(err, ErrCircuitOpen) 返回 true
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// CircuitState 定义了熔断器的状态
+type CircuitState int
+
+const (
+	// CircuitClosed 关闭状态，请求正常通过
+	CircuitClosed CircuitState = iota
+	// CircuitOpen 打开状态，请求被直接拒绝
+	CircuitOpen
+	// CircuitHalfOpen 半开状态，允许少量试探请求通过
+	CircuitHalfOpen
+)
+
+// String 返回熔断器状态的可读名称
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("CircuitState(%d)", int(s))
+	}
+}
+
+// CircuitStateEvent 熔断器状态变化事件
+type CircuitStateEvent struct {
+	Event
+
+	// From 变化前的状态
+	From CircuitState
+
+	// To 变化后的状态
+	To CircuitState
+
+	// Err 触发状态变化的最后一次发送错误（恢复为关闭状态时为 nil）
+	Err error
+}
+
+// CircuitBreakerMailer 是一个为其它 Mailer 增加熔断保护的包装器
+//
+// 连续失败达到 FailureThreshold 次后熔断器打开，此后的发送请求直接返回
+// *CircuitOpenError 而不会再访问底层传输；经过 CoolDown 后进入半开状态，
+// 允许 HalfOpenRequests 个试探请求通过，全部成功则恢复关闭，任一失败则重新打开
+//
+// 示例:
+//
+//	breaker := &CircuitBreakerMailer{
+//		Mailer:           client,
+//		FailureThreshold: 5,
+//		CoolDown:         30 * time.Second,
+//	}
+//	breaker.OnStateChange().BindFunc(func(e *CircuitStateEvent) error {
+//		log.Printf("熔断器状态: %s -> %s", e.From, e.To)
+//		return e.Next()
+//	})
+type CircuitBreakerMailer struct {
+	// onStateChange 状态变化钩子
+	onStateChange *Hook[*CircuitStateEvent]
+
+	// Mailer 实际执行发送的邮件客户端
+	Mailer Mailer
+
+	// FailureThreshold 触发熔断所需的连续失败次数
+	// 如果未明确设置，默认为 5
+	FailureThreshold int
+
+	// CoolDown 熔断器打开后进入半开状态前的等待时间
+	// 如果未明确设置，默认为 30 秒
+	CoolDown time.Duration
+
+	// HalfOpenRequests 半开状态下允许通过的试探请求数
+	// 如果未明确设置，默认为 1
+	HalfOpenRequests int
+
+	// IsFailure 可选的错误分类函数，返回 true 的错误才计入失败次数
+	// 如果未设置，除 context 取消、超时、限流（ErrRateLimited）、邮件校验失败（ErrInvalidMessage）
+	// 以及收件人被服务器拒绝（所有失败原因都是 SMTP 响应的 *DeliveryError）外的所有错误都计为失败
+	IsFailure func(err error) bool
+
+	mu        sync.Mutex
+	state     CircuitState
+	failures  int
+	openedAt  time.Time
+	inFlight  int
+	successes int
+}
+
+// OnStateChange 返回熔断器状态变化钩子
+//
+// 钩子在状态变化后同步触发，处理器返回的错误会被忽略，不影响发送结果
+func (b *CircuitBreakerMailer) OnStateChange() *Hook[*CircuitStateEvent] {
+	if b.onStateChange == nil {
+		b.onStateChange = &Hook[*CircuitStateEvent]{}
+	}
+	return b.onStateChange
+}
+
+// State 返回熔断器当前的状态
+func (b *CircuitBreakerMailer) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == CircuitOpen && time.Since(b.openedAt) >= b.coolDown() {
+		return CircuitHalfOpen
+	}
+
+	return b.state
+}
+
+// Send 实现 Mailer 接口
+// 等价于 SendContext(context.Background(), m)
+func (b *CircuitBreakerMailer) Send(m *Message) error {
+	return b.SendContext(context.Background(), m)
+}
+
+// SendContext 实现 ContextMailer 接口
+// 熔断器允许时调用被包装的 Mailer 发送邮件，并根据结果更新熔断器状态
+//
+// 参数:
+//   - ctx: 传递给被包装 Mailer 的 context
+//   - m: 要发送的邮件消息
+//
+// 返回:
+//   - error: 熔断器打开时返回 *CircuitOpenError，否则返回发送结果
+func (b *CircuitBreakerMailer) SendContext(ctx context.Context, m *Message) error {
+	return b.sendVia(ctx, b.Mailer, m)
+}
+
+// sendVia 熔断器允许时通过 target（被包装的 Mailer 或其会话）发送邮件并记录结果
+func (b *CircuitBreakerMailer) sendVia(ctx context.Context, target Mailer, m *Message) error {
+	if b.Mailer == nil {
+		return errors.New("circuit breaker has no underlying mailer")
+	}
+
+	if err := b.allow(); err != nil {
+		return err
+	}
+
+	err := sendWithContext(ctx, target, m)
+
+	b.record(err)
+
+	return err
+}
+
+// allow 检查当前是否允许发送，必要时将打开状态切换为半开状态
+func (b *CircuitBreakerMailer) allow() error {
+	b.mu.Lock()
+
+	var change *CircuitStateEvent
+
+	if b.state == CircuitOpen {
+		elapsed := time.Since(b.openedAt)
+		if elapsed < b.coolDown() {
+			b.mu.Unlock()
+			return &CircuitOpenError{State: CircuitOpen, RetryAfter: b.coolDown() - elapsed}
+		}
+		change = b.setState(CircuitHalfOpen, nil)
+	}
+
+	if b.state == CircuitHalfOpen {
+		if b.inFlight >= b.halfOpenRequests() {
+			b.mu.Unlock()
+			b.notify(change)
+			return &CircuitOpenError{State: CircuitHalfOpen}
+		}
+		b.inFlight++
+	}
+
+	b.mu.Unlock()
+	b.notify(change)
+
+	return nil
+}
+
+// record 根据一次发送的结果更新熔断器状态
+func (b *CircuitBreakerMailer) record(err error) {
+	failed := err != nil && b.isFailure(err)
+
+	b.mu.Lock()
+
+	var change *CircuitStateEvent
+
+	switch b.state {
+	case CircuitHalfOpen:
+		if b.inFlight > 0 {
+			b.inFlight--
+		}
+		if failed {
+			change = b.setState(CircuitOpen, err)
+		} else if err == nil {
+			b.successes++
+			if b.successes >= b.halfOpenRequests() {
+				change = b.setState(CircuitClosed, nil)
+			}
+		}
+	case CircuitClosed:
+		if failed {
+			b.failures++
+			if b.failures >= b.failureThreshold() {
+				change = b.setState(CircuitOpen, err)
+			}
+		} else if err == nil {
+			b.failures = 0
+		}
+	}
+
+	b.mu.Unlock()
+	b.notify(change)
+}
+
+// setState 切换熔断器状态并重置计数器，调用方需持有锁
+//
+// 返回需要在释放锁之后通知的状态变化事件
+func (b *CircuitBreakerMailer) setState(state CircuitState, err error) *CircuitStateEvent {
+	if b.state == state {
+		return nil
+	}
+
+	event := &CircuitStateEvent{From: b.state, To: state, Err: err}
+
+	b.state = state
+	b.failures = 0
+	b.successes = 0
+	b.inFlight = 0
+
+	if state == CircuitOpen {
+		b.openedAt = time.Now()
+	}
+
+	return event
+}
+
+// notify 触发状态变化钩子（必须在未持有锁时调用）
+func (b *CircuitBreakerMailer) notify(event *CircuitStateEvent) {
+	if event == nil || b.onStateChange == nil {
+		return
+	}
+
+	_ = b.onStateChange.Trigger(event)
+}
+
+// isFailure 判断错误是否计入熔断失败次数
+func (b *CircuitBreakerMailer) isFailure(err error) bool {
+	if b.IsFailure != nil {
+		return b.IsFailure(err)
+	}
+
+	return !errors.Is(err, context.Canceled) &&
+		!errors.Is(err, context.DeadlineExceeded) &&
+		!errors.Is(err, ErrRateLimited) &&
+		!errors.Is(err, ErrInvalidMessage) &&
+		!recipientsRejected(err)
+}
+
+// recipientsRejected 判断错误是否只是服务器拒绝了部分或全部收件人（如 550 用户不存在）
+//
+// 这类错误说明服务器工作正常，问题在于收件人地址，不应使其它收件人的邮件被熔断
+func recipientsRejected(err error) bool {
+	var deliveryErr *DeliveryError
+	if !errors.As(err, &deliveryErr) || len(deliveryErr.Recipients) == 0 {
+		return false
+	}
+
+	for _, r := range deliveryErr.Recipients {
+		if !isSMTPReply(r.Err) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// failureThreshold 返回生效的失败阈值
+func (b *CircuitBreakerMailer) failureThreshold() int {
+	if b.FailureThreshold <= 0 {
+		return 5
+	}
+	return b.FailureThreshold
+}
+
+// coolDown 返回生效的冷却时间
+func (b *CircuitBreakerMailer) coolDown() time.Duration {
+	if b.CoolDown <= 0 {
+		return 30 * time.Second
+	}
+	return b.CoolDown
+}
+
+// halfOpenRequests 返回生效的半开试探请求数
+func (b *CircuitBreakerMailer) halfOpenRequests() int {
+	if b.HalfOpenRequests <= 0 {
+		return 1
+	}
+	return b.HalfOpenRequests
+}
@@ -0,0 +1,188 @@
+package gomailer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"net/textproto"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerIgnoresRejectedRecipients(t *testing.T) {
+	f := newFakeSMTP(t)
+	f.Reject = map[string]string{}
+	for i := 0; i < 10; i++ {
+		f.Reject[fmt.Sprintf("missing%d@example.com", i)] = "550 5.1.1 no such user"
+	}
+
+	breaker := &CircuitBreakerMailer{Mailer: f.client(), FailureThreshold: 5}
+	for i := 0; i < 10; i++ {
+		err := breaker.Send(&Message{
+			From: mail.Address{Address: "sender@example.com"},
+			To:   []mail.Address{{Address: fmt.Sprintf("missing%d@example.com", i)}},
+			Text: "hello",
+		})
+		var deliveryErr *DeliveryError
+		if !errors.As(err, &deliveryErr) {
+			t.Fatalf("send %d: err = %v, want *DeliveryError", i, err)
+		}
+	}
+
+	if state := breaker.State(); state != CircuitClosed {
+		t.Errorf("state = %s, want closed", state)
+	}
+}
+
+func TestCircuitBreakerIsFailure(t *testing.T) {
+	reply := &textproto.Error{Code: 550, Msg: "no such user"}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"connection error", io.ErrUnexpectedEOF, true},
+		{"invalid message", ValidationErrors{{Field: "from", Message: "from address is required"}}, false},
+		{"rejected recipients", &DeliveryError{Recipients: []*RecipientError{{Address: "a@example.com", Err: reply}}, Total: 2}, false},
+		{"connection lost mid batch", &DeliveryError{Recipients: []*RecipientError{
+			{Address: "a@example.com", Err: reply},
+			{Address: "b@example.com", Err: io.ErrUnexpectedEOF},
+		}, Total: 2}, true},
+	}
+
+	breaker := &CircuitBreakerMailer{}
+	for _, tt := range tests {
+		if got := breaker.isFailure(tt.err); got != tt.want {
+			t.Errorf("%s: isFailure = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// transitions 记录熔断器状态变化事件
+func transitions(b *CircuitBreakerMailer) func() []string {
+	var mu sync.Mutex
+	var events []string
+
+	b.OnStateChange().BindFunc(func(e *CircuitStateEvent) error {
+		mu.Lock()
+		events = append(events, e.From.String()+"->"+e.To.String())
+		mu.Unlock()
+		return e.Next()
+	})
+
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), events...)
+	}
+}
+
+func TestCircuitBreakerStateMachine(t *testing.T) {
+	inner := &recordingMailer{err: io.ErrUnexpectedEOF}
+	breaker := &CircuitBreakerMailer{Mailer: inner, FailureThreshold: 3, CoolDown: 30 * time.Millisecond}
+	events := transitions(breaker)
+
+	// 未达到阈值前保持关闭，成功会重置计数
+	for i := 0; i < 2; i++ {
+		breaker.Send(messageTo("a@example.com"))
+	}
+	inner.err = nil
+	breaker.Send(messageTo("a@example.com"))
+	inner.err = io.ErrUnexpectedEOF
+	for i := 0; i < 2; i++ {
+		breaker.Send(messageTo("a@example.com"))
+	}
+	if state := breaker.State(); state != CircuitClosed {
+		t.Fatalf("state = %s, want closed", state)
+	}
+
+	// closed -> open
+	breaker.Send(messageTo("a@example.com"))
+	if state := breaker.State(); state != CircuitOpen {
+		t.Fatalf("state = %s, want open", state)
+	}
+
+	sent := inner.count()
+	var openErr *CircuitOpenError
+	if err := breaker.Send(messageTo("a@example.com")); !errors.As(err, &openErr) || openErr.State != CircuitOpen || openErr.RetryAfter <= 0 {
+		t.Fatalf("err = %v, want *CircuitOpenError with RetryAfter", err)
+	}
+	if inner.count() != sent {
+		t.Fatal("open breaker must not call the underlying mailer")
+	}
+
+	// open -> half-open -> open：试探失败
+	time.Sleep(40 * time.Millisecond)
+	if state := breaker.State(); state != CircuitHalfOpen {
+		t.Fatalf("state = %s, want half-open after cool down", state)
+	}
+	if err := breaker.Send(messageTo("a@example.com")); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("probe err = %v", err)
+	}
+	if state := breaker.State(); state != CircuitOpen {
+		t.Fatalf("state = %s, want open after failed probe", state)
+	}
+
+	// open -> half-open -> closed：试探成功
+	time.Sleep(40 * time.Millisecond)
+	inner.err = nil
+	if err := breaker.Send(messageTo("a@example.com")); err != nil {
+		t.Fatal(err)
+	}
+	if state := breaker.State(); state != CircuitClosed {
+		t.Fatalf("state = %s, want closed after successful probe", state)
+	}
+
+	want := []string{
+		"closed->open",
+		"open->half-open", "half-open->open",
+		"open->half-open", "half-open->closed",
+	}
+	if got := events(); !reflect.DeepEqual(got, want) {
+		t.Errorf("transitions = %q, want %q", got, want)
+	}
+}
+
+// blockingMailer 在 release 关闭前阻塞发送
+type blockingMailer struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingMailer) Send(m *Message) error {
+	b.started <- struct{}{}
+	<-b.release
+	return nil
+}
+
+func TestCircuitBreakerHalfOpenAllowsSingleProbe(t *testing.T) {
+	inner := &recordingMailer{err: io.ErrUnexpectedEOF}
+	breaker := &CircuitBreakerMailer{Mailer: inner, FailureThreshold: 1, CoolDown: 10 * time.Millisecond}
+	breaker.Send(messageTo("a@example.com"))
+	time.Sleep(20 * time.Millisecond)
+
+	blocking := &blockingMailer{started: make(chan struct{}, 1), release: make(chan struct{})}
+	breaker.Mailer = blocking
+
+	done := make(chan error, 1)
+	go func() { done <- breaker.Send(messageTo("a@example.com")) }()
+	<-blocking.started
+
+	// 试探请求进行中，其它请求被拒绝
+	var openErr *CircuitOpenError
+	if err := breaker.Send(messageTo("a@example.com")); !errors.As(err, &openErr) || openErr.State != CircuitHalfOpen {
+		t.Fatalf("err = %v, want *CircuitOpenError in half-open state", err)
+	}
+
+	close(blocking.release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if state := breaker.State(); state != CircuitClosed {
+		t.Errorf("state = %s, want closed", state)
+	}
+}
@@ -0,0 +1,289 @@
+package gomailer
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"maps"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"slices"
+)
+
+// Clone 返回邮件的深拷贝，副本可以与原邮件分别修改和发送（包括并发发送）
+//
+// 地址、邮件头、会议邀请等全部复制；附件内容按来源处理：
+//   - Path、FS 和 ReaderAt 来源每次发送时重新打开或读取，直接复制
+//   - 同时实现了 io.ReaderAt 和 io.Seeker 的 Reader（如 *os.File、*bytes.Reader）
+//     在副本中替换为从当前位置开始的独立 *io.SectionReader，不会读入内存
+//   - 其它 Reader 读入内存，副本使用 *bytes.Reader
+//
+// 注意：如果原邮件中有只能读取一次的 Reader（没有实现 io.Seeker，如 HTTP 请求体），
+// Clone 会修改原邮件：这些 Reader 被读完后，原邮件的 Files、Attachments 和 InlineAttachments
+// 中对应的 Reader 被替换为包含相同内容的 *bytes.Reader，使原邮件仍然可以发送。
+// 其它 Reader 不会被替换，但复制时会通过 Seek 定位并恢复原来的位置。
+// 因此原邮件有 Reader 来源的附件时，Clone 不能与原邮件的其它使用（发送、序列化、另一次 Clone）
+// 并发执行；附件只使用 Path、FS 或 ReaderAt 来源时原邮件不会被修改，可以并发调用 Clone
+//
+// 返回:
+//   - *Message: 邮件的副本
+//   - error: 读取附件内容失败时返回错误
+func (m *Message) Clone() (*Message, error) {
+	c := *m
+
+	c.To = slices.Clone(m.To)
+	c.Cc = slices.Clone(m.Cc)
+	c.Bcc = slices.Clone(m.Bcc)
+	c.ReplyTo = slices.Clone(m.ReplyTo)
+	c.ReadReceiptTo = slices.Clone(m.ReadReceiptTo)
+	c.References = slices.Clone(m.References)
+	c.Headers = maps.Clone(m.Headers)
+
+	if m.DeliveryNotification != nil {
+		d := *m.DeliveryNotification
+		d.Notify = slices.Clone(m.DeliveryNotification.Notify)
+		c.DeliveryNotification = &d
+	}
+
+	if m.Unsubscribe != nil {
+		u := *m.Unsubscribe
+		c.Unsubscribe = &u
+	}
+
+	if m.Calendar != nil {
+		e := *m.Calendar
+		e.Attendees = slices.Clone(m.Calendar.Attendees)
+		c.Calendar = &e
+	}
+
+	if m.Files != nil {
+		c.Files = slices.Clone(m.Files)
+		for i := range m.Files {
+			if m.Files[i].Reader == nil {
+				continue
+			}
+
+			original, copied, err := cloneReader(m.Files[i].Reader)
+			if err != nil {
+				return nil, fmt.Errorf("attachment %q: %w", m.Files[i].name(), err)
+			}
+			if original != m.Files[i].Reader {
+				m.Files[i].Reader = original
+			}
+			c.Files[i].Reader = copied
+		}
+	}
+
+	var err error
+	if c.Attachments, err = cloneAttachmentMap(m.Attachments); err != nil {
+		return nil, err
+	}
+	if c.InlineAttachments, err = cloneAttachmentMap(m.InlineAttachments); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// cloneAttachmentMap 复制 Attachments/InlineAttachments 映射，必要时替换原映射中的 Reader
+func cloneAttachmentMap(files map[string]io.Reader) (map[string]io.Reader, error) {
+	if files == nil {
+		return nil, nil
+	}
+
+	out := make(map[string]io.Reader, len(files))
+	for name, r := range files {
+		if r == nil {
+			out[name] = nil
+			continue
+		}
+
+		original, copied, err := cloneReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("attachment %q: %w", name, err)
+		}
+		if original != r {
+			files[name] = original
+		}
+		out[name] = copied
+	}
+
+	return out, nil
+}
+
+// cloneReader 为 r 创建一个可以独立读取的副本
+//
+// 返回之后代替 r 使用的 Reader（r 没有被读取时就是 r 本身）和副本
+func cloneReader(r io.Reader) (original, copied io.Reader, err error) {
+	ra, isReaderAt := r.(io.ReaderAt)
+	s, isSeeker := r.(io.Seeker)
+	if isReaderAt && isSeeker {
+		offset, err := s.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return r, nil, err
+		}
+
+		end, err := s.Seek(0, io.SeekEnd)
+		if _, serr := s.Seek(offset, io.SeekStart); err == nil {
+			err = serr
+		}
+		if err != nil {
+			return r, nil, err
+		}
+
+		return r, io.NewSectionReader(ra, offset, end-offset), nil
+	}
+
+	// 只能读取一次的 Reader 读完后由 readReplayable 替换为 *bytes.Reader
+	data, replacement, err := readReplayable(r)
+	if err != nil {
+		return replacement, nil, err
+	}
+
+	return replacement, bytes.NewReader(data), nil
+}
+
+// readReplayable 读取 r 的全部内容，并返回之后代替 r 使用的 Reader
+//
+// 实现了 io.Seeker 的 r 读取后回到原来的位置并原样返回；
+// 其它 Reader 已被读完，返回包含相同内容的 *bytes.Reader
+func readReplayable(r io.Reader) ([]byte, io.Reader, error) {
+	s, ok := r.(io.Seeker)
+	if !ok {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, r, err
+		}
+		return data, bytes.NewReader(data), nil
+	}
+
+	offset, err := s.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, r, err
+	}
+
+	data, err := io.ReadAll(r)
+	if _, serr := s.Seek(offset, io.SeekStart); err == nil {
+		err = serr
+	}
+
+	return data, r, err
+}
+
+// Recipient 是个性化发送的一个收件人
+type Recipient struct {
+	// Address 收件人地址
+	Address mail.Address `json:"address"`
+
+	// Vars 替换邮件中 {{name}} 形式占位符的变量
+	//
+	// 未设置时 {{name}} 和 {{email}} 分别替换为收件人的姓名和地址
+	Vars map[string]string `json:"vars,omitempty"`
+}
+
+// placeholderPattern 匹配 {{name}} 形式的占位符，名称两侧可以有空格
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.-]+)\s*\}\}`)
+
+// Personalize 以邮件为模板，为每个收件人生成一封单独的邮件
+//
+// 与 Clone 一样，只能读取一次的附件 Reader 在模板中会被替换为 *bytes.Reader
+//
+// 每封邮件都是 Clone 得到的副本：To 只包含该收件人，Cc、Bcc 和 MessageID 被清空
+// （发送时为每封邮件生成不同的 Message-ID）。以下内容中的 {{name}} 占位符会被替换为
+// 收件人的变量：
+//   - Subject、Text 和自定义邮件头：原样替换
+//   - HTML：变量值按 HTML 转义，避免插入标签
+//   - Unsubscribe.URL：变量值按 URL 查询参数转义，用于生成每个收件人的退订链接
+//
+// 使用了未定义的变量时返回错误，避免把占位符原样发送给收件人
+//
+// 示例:
+//
+//	template := &gomailer.Message{
+//		From:    mail.Address{Address: "news@example.com"},
+//		Subject: "{{name}}，本周精选",
+//		HTML:    "<p>亲爱的 {{name}}：</p>",
+//	}
+//	messages, err := template.Personalize([]gomailer.Recipient{
+//		{Address: mail.Address{Name: "张三", Address: "zhangsan@example.com"}},
+//		{Address: mail.Address{Address: "lisi@example.com"}, Vars: map[string]string{"name": "李四"}},
+//	})
+//	result := gomailer.SendMany(ctx, client, messages, 4)
+//
+// 参数:
+//   - recipients: 收件人及其变量
+//
+// 返回:
+//   - []*Message: 与 recipients 一一对应的邮件
+//   - error: 复制附件失败或使用了未定义的变量时返回错误
+func (m *Message) Personalize(recipients []Recipient) ([]*Message, error) {
+	// 先复制一次，使只能读取一次的附件变为可以重复读取，之后的复制不再读取内容
+	base, err := m.Clone()
+	if err != nil {
+		return nil, err
+	}
+	base.Cc, base.Bcc = nil, nil
+	base.MessageID = ""
+
+	messages := make([]*Message, len(recipients))
+	for i, rcpt := range recipients {
+		c, err := base.Clone()
+		if err != nil {
+			return nil, err
+		}
+		c.To = []mail.Address{rcpt.Address}
+
+		if err := c.substitute(rcpt.vars()); err != nil {
+			return nil, fmt.Errorf("recipient %d (%s): %w", i, rcpt.Address.Address, err)
+		}
+
+		messages[i] = c
+	}
+
+	return messages, nil
+}
+
+// vars 返回收件人的变量，包括默认的 name 和 email
+func (r *Recipient) vars() map[string]string {
+	vars := map[string]string{
+		"name":  r.Address.Name,
+		"email": r.Address.Address,
+	}
+	maps.Copy(vars, r.Vars)
+	return vars
+}
+
+// substitute 替换邮件中的占位符
+func (m *Message) substitute(vars map[string]string) error {
+	var err error
+	replace := func(s string, escape func(string) string) string {
+		return placeholderPattern.ReplaceAllStringFunc(s, func(placeholder string) string {
+			name := placeholderPattern.FindStringSubmatch(placeholder)[1]
+			value, ok := vars[name]
+			if !ok {
+				if err == nil {
+					err = fmt.Errorf("undefined variable %q", name)
+				}
+				return placeholder
+			}
+			return escape(value)
+		})
+	}
+	plain := func(s string) string { return s }
+
+	m.Subject = replace(m.Subject, plain)
+	m.Text = replace(m.Text, plain)
+	m.HTML = replace(m.HTML, html.EscapeString)
+
+	for name, value := range m.Headers {
+		m.Headers[name] = replace(value, plain)
+	}
+
+	if m.Unsubscribe != nil {
+		m.Unsubscribe.URL = replace(m.Unsubscribe.URL, url.QueryEscape)
+	}
+
+	return err
+}
@@ -0,0 +1,221 @@
+package gomailer
+
+import (
+	"bytes"
+	"io"
+	"net/mail"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// cloneTemplate 返回一封所有引用类型字段都已设置的邮件
+func cloneTemplate() *Message {
+	return &Message{
+		From:          mail.Address{Address: "sender@example.com"},
+		To:            []mail.Address{{Address: "to@example.com"}},
+		Cc:            []mail.Address{{Address: "cc@example.com"}},
+		Bcc:           []mail.Address{{Address: "bcc@example.com"}},
+		ReplyTo:       []mail.Address{{Address: "reply@example.com"}},
+		ReadReceiptTo: []mail.Address{{Address: "legal@example.com"}},
+		References:    []string{"a@example.com"},
+		Subject:       "subject",
+		Text:          "text",
+		Headers:       map[string]string{"X-Campaign": "spring"},
+		Unsubscribe:   &Unsubscribe{URL: "https://example.com/u"},
+		DeliveryNotification: &DeliveryNotification{
+			Notify: []DSNNotify{DSNNotifyFailure},
+		},
+		Calendar: &CalendarEvent{
+			Summary:   "review",
+			Start:     time.Date(2026, 10, 20, 9, 0, 0, 0, time.UTC),
+			End:       time.Date(2026, 10, 20, 10, 0, 0, 0, time.UTC),
+			Attendees: []CalendarAttendee{{Address: mail.Address{Address: "to@example.com"}}},
+		},
+		Files:       []Attachment{{Filename: "a.txt", Reader: strings.NewReader("file")}},
+		Attachments: map[string]io.Reader{"b.txt": strings.NewReader("attachment")},
+	}
+}
+
+func TestCloneIsIndependent(t *testing.T) {
+	m := cloneTemplate()
+	want := cloneTemplate()
+
+	c, err := m.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 修改副本的每个引用类型字段
+	c.To[0].Address = "changed@example.com"
+	c.Cc[0].Address = "changed@example.com"
+	c.Bcc[0].Address = "changed@example.com"
+	c.ReplyTo[0].Address = "changed@example.com"
+	c.ReadReceiptTo[0].Address = "changed@example.com"
+	c.References[0] = "changed@example.com"
+	c.Headers["X-Campaign"] = "changed"
+	c.Headers["X-New"] = "new"
+	c.Unsubscribe.URL = "https://example.com/changed"
+	c.DeliveryNotification.Notify[0] = DSNNotifyNever
+	c.Calendar.Summary = "changed"
+	c.Calendar.Attendees[0].Address.Address = "changed@example.com"
+	c.Files[0].Filename = "changed.txt"
+	c.Attachments["new.txt"] = strings.NewReader("new")
+	delete(c.Attachments, "b.txt")
+
+	if !reflect.DeepEqual(m.To, want.To) || !reflect.DeepEqual(m.Cc, want.Cc) || !reflect.DeepEqual(m.Bcc, want.Bcc) ||
+		!reflect.DeepEqual(m.ReplyTo, want.ReplyTo) || !reflect.DeepEqual(m.ReadReceiptTo, want.ReadReceiptTo) {
+		t.Errorf("addresses of the original changed: %+v", m)
+	}
+	if !reflect.DeepEqual(m.References, want.References) || !reflect.DeepEqual(m.Headers, want.Headers) {
+		t.Errorf("References = %q, Headers = %v", m.References, m.Headers)
+	}
+	if *m.Unsubscribe != *want.Unsubscribe || !reflect.DeepEqual(m.DeliveryNotification, want.DeliveryNotification) {
+		t.Errorf("Unsubscribe = %+v, DeliveryNotification = %+v", m.Unsubscribe, m.DeliveryNotification)
+	}
+	if m.Calendar.Summary != "review" || !reflect.DeepEqual(m.Calendar.Attendees, want.Calendar.Attendees) {
+		t.Errorf("Calendar = %+v", m.Calendar)
+	}
+	if m.Files[0].Filename != "a.txt" || len(m.Attachments) != 1 || m.Attachments["b.txt"] == nil {
+		t.Errorf("Files = %+v, Attachments = %v", m.Files, m.Attachments)
+	}
+}
+
+func TestCloneReaders(t *testing.T) {
+	seekable := strings.NewReader("skip:seekable")
+	seekable.Seek(int64(len("skip:")), io.SeekStart)
+	once := &onceReader{r: strings.NewReader("once")}
+
+	m := &Message{
+		Files: []Attachment{
+			{Filename: "seekable.txt", Reader: seekable},
+			{Filename: "once.txt", Reader: once},
+		},
+		InlineAttachments: map[string]io.Reader{"logo.png": &onceReader{r: strings.NewReader("png")}},
+	}
+
+	c, err := m.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 可随机读取的 Reader 在原邮件中保持不变，副本从当前位置开始独立读取
+	if m.Files[0].Reader != seekable {
+		t.Error("seekable reader of the original was replaced")
+	}
+	if data, _ := io.ReadAll(c.Files[0].Reader); string(data) != "seekable" {
+		t.Errorf("copy = %q, want content from the current offset", data)
+	}
+	if data, _ := io.ReadAll(seekable); string(data) != "seekable" {
+		t.Errorf("original = %q, reading the copy must not move the original", data)
+	}
+
+	// 只能读取一次的 Reader 在原邮件和副本中都替换为内存中的内容
+	for name, pair := range map[string][2]io.Reader{
+		"once.txt": {m.Files[1].Reader, c.Files[1].Reader},
+		"logo.png": {m.InlineAttachments["logo.png"], c.InlineAttachments["logo.png"]},
+	} {
+		if _, ok := pair[0].(*bytes.Reader); !ok {
+			t.Errorf("%s: original reader is %T, want *bytes.Reader", name, pair[0])
+		}
+		a, _ := io.ReadAll(pair[0])
+		b, _ := io.ReadAll(pair[1])
+		if len(a) == 0 || !bytes.Equal(a, b) {
+			t.Errorf("%s: original = %q, copy = %q", name, a, b)
+		}
+	}
+}
+
+func TestCloneConcurrentWithoutReaders(t *testing.T) {
+	m := cloneTemplate()
+	m.Files = []Attachment{{Filename: "a.txt", ReaderAt: strings.NewReader("file"), Size: 4}}
+	m.Attachments = nil
+
+	// 附件不使用 Reader 来源时原邮件不会被修改，可以并发复制（配合 -race 检查）
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := m.Clone(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestPersonalize(t *testing.T) {
+	template := &Message{
+		From:        mail.Address{Address: "news@example.com"},
+		To:          []mail.Address{{Address: "template@example.com"}},
+		Cc:          []mail.Address{{Address: "cc@example.com"}},
+		Bcc:         []mail.Address{{Address: "bcc@example.com"}},
+		MessageID:   "template@example.com",
+		Subject:     "{{name}}，本周精选",
+		Text:        "Hi {{ name }}, code {{code}} <b>",
+		HTML:        "<p>亲爱的 {{name}}：{{code}}</p>",
+		Headers:     map[string]string{"X-Code": "{{code}}"},
+		Unsubscribe: &Unsubscribe{URL: "https://example.com/u?email={{email}}"},
+		Attachments: map[string]io.Reader{"catalog.txt": &onceReader{r: strings.NewReader("catalog")}},
+	}
+
+	messages, err := template.Personalize([]Recipient{
+		{Address: mail.Address{Name: "张三", Address: "zhangsan@example.com"}, Vars: map[string]string{"code": "<A&1>"}},
+		{Address: mail.Address{Address: "li+si@example.com"}, Vars: map[string]string{"name": "李四", "code": "B2"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("messages = %d, want 2", len(messages))
+	}
+
+	first, second := messages[0], messages[1]
+	if !reflect.DeepEqual(first.To, []mail.Address{{Name: "张三", Address: "zhangsan@example.com"}}) || first.Cc != nil || first.Bcc != nil || first.MessageID != "" {
+		t.Errorf("first = To %v, Cc %v, Bcc %v, MessageID %q", first.To, first.Cc, first.Bcc, first.MessageID)
+	}
+
+	tests := []struct{ field, got, want string }{
+		{"Subject", first.Subject, "张三，本周精选"},
+		{"Text", first.Text, "Hi 张三, code <A&1> <b>"},
+		{"HTML", first.HTML, "<p>亲爱的 张三：&lt;A&amp;1&gt;</p>"},
+		{"Headers", first.Headers["X-Code"], "<A&1>"},
+		{"Unsubscribe.URL", first.Unsubscribe.URL, "https://example.com/u?email=zhangsan%40example.com"},
+		{"second Subject", second.Subject, "李四，本周精选"},
+		{"second Unsubscribe.URL", second.Unsubscribe.URL, "https://example.com/u?email=li%2Bsi%40example.com"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.field, tt.got, tt.want)
+		}
+	}
+
+	// 模板保持不变，每封邮件都有自己的附件副本
+	if template.Subject != "{{name}}，本周精选" || template.Headers["X-Code"] != "{{code}}" || template.Unsubscribe.URL != "https://example.com/u?email={{email}}" {
+		t.Errorf("template modified: %+v", template)
+	}
+	for i, m := range messages {
+		if data, _ := io.ReadAll(m.Attachments["catalog.txt"]); string(data) != "catalog" {
+			t.Errorf("message %d attachment = %q", i, data)
+		}
+	}
+}
+
+func TestPersonalizeUndefinedVariable(t *testing.T) {
+	template := &Message{
+		From:    mail.Address{Address: "news@example.com"},
+		Subject: "Hello {{name}}",
+		HTML:    "<p>{{coupon}}</p>",
+	}
+
+	_, err := template.Personalize([]Recipient{
+		{Address: mail.Address{Name: "A", Address: "a@example.com"}, Vars: map[string]string{"coupon": "X"}},
+		{Address: mail.Address{Name: "B", Address: "b@example.com"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), `"coupon"`) || !strings.Contains(err.Error(), "b@example.com") {
+		t.Errorf("err = %v, want undefined variable error for the second recipient", err)
+	}
+}
@@ -0,0 +1,142 @@
+package gomailer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DSNNotify 是请求投递状态通知的事件（RFC 3461 第 4.1 节）
+type DSNNotify string
+
+const (
+	// DSNNotifySuccess 投递成功时通知（送达回执）
+	DSNNotifySuccess DSNNotify = "SUCCESS"
+
+	// DSNNotifyFailure 投递失败时通知
+	DSNNotifyFailure DSNNotify = "FAILURE"
+
+	// DSNNotifyDelay 投递延迟时通知
+	DSNNotifyDelay DSNNotify = "DELAY"
+
+	// DSNNotifyNever 任何情况下都不通知（包括退信），不能与其它事件同时使用
+	DSNNotifyNever DSNNotify = "NEVER"
+)
+
+// DSNReturn 指定失败通知中附带原邮件的方式（RFC 3461 第 4.3 节）
+type DSNReturn string
+
+const (
+	// DSNReturnFull 附带完整的原邮件
+	DSNReturnFull DSNReturn = "FULL"
+
+	// DSNReturnHeaders 只附带原邮件的邮件头
+	DSNReturnHeaders DSNReturn = "HDRS"
+)
+
+// maxEnvelopeIDLength 是 ENVID 参数的最大长度（RFC 3461 第 4.4 节）
+const maxEnvelopeIDLength = 100
+
+// DeliveryNotification 请求投递状态通知（DSN，RFC 3461），即送达回执
+//
+// 与阅读回执不同，DSN 由邮件服务器在投递时生成，不需要收件人操作，通知发送到信封发件人。
+// SMTPClient 在服务器声明 DSN 扩展时生成 MAIL FROM 的 RET、ENVID 参数和 RCPT TO 的 NOTIFY 参数，
+// 服务器不支持时按普通邮件发送（RFC 3461 不允许向不支持的服务器发送这些参数）；
+// Sendmail 通过 -N、-R 和 -V 选项传递。SendRaw 发送的原始邮件不支持 DSN
+type DeliveryNotification struct {
+	// Notify 需要通知的事件，为空时为 DSNNotifySuccess 和 DSNNotifyFailure
+	Notify []DSNNotify `json:"notify,omitempty"`
+
+	// Return 失败通知中附带原邮件的方式，为空时由服务器决定
+	Return DSNReturn `json:"return,omitempty"`
+
+	// EnvelopeID 信封 ID，服务器在通知中原样返回（Original-Envelope-Id 字段），用于关联发送记录
+	// 只能包含可打印 ASCII 字符，最长 100 个字符
+	EnvelopeID string `json:"envelopeId,omitempty"`
+}
+
+// notify 返回 NOTIFY 参数的取值
+func (d *DeliveryNotification) notify() []string {
+	if len(d.Notify) == 0 {
+		return []string{string(DSNNotifySuccess), string(DSNNotifyFailure)}
+	}
+
+	values := make([]string, len(d.Notify))
+	for i, n := range d.Notify {
+		values[i] = strings.ToUpper(string(n))
+	}
+	return values
+}
+
+// validate 检查投递状态通知设置，问题记录到 errs
+func (d *DeliveryNotification) validate(errs *ValidationErrors, field string) {
+	for i, n := range d.Notify {
+		switch DSNNotify(strings.ToUpper(string(n))) {
+		case DSNNotifySuccess, DSNNotifyFailure, DSNNotifyDelay:
+		case DSNNotifyNever:
+			if len(d.Notify) > 1 {
+				errs.add(fmt.Sprintf("%s.notify[%d]", field, i), "NEVER cannot be combined with other notify values")
+			}
+		default:
+			errs.add(fmt.Sprintf("%s.notify[%d]", field, i), "invalid notify value %q", n)
+		}
+	}
+
+	switch DSNReturn(strings.ToUpper(string(d.Return))) {
+	case "", DSNReturnFull, DSNReturnHeaders:
+	default:
+		errs.add(field+".return", "invalid return value %q", d.Return)
+	}
+
+	if len(d.EnvelopeID) > maxEnvelopeIDLength {
+		errs.add(field+".envelopeId", "envelope id is longer than %d characters", maxEnvelopeIDLength)
+	}
+	for _, r := range d.EnvelopeID {
+		if r < '!' || r > '~' {
+			errs.add(field+".envelopeId", "envelope id must contain only printable ASCII characters")
+			break
+		}
+	}
+}
+
+// mailParams 返回 MAIL FROM 命令的 RET 和 ENVID 参数
+func (d *DeliveryNotification) mailParams() []string {
+	var params []string
+	if d.Return != "" {
+		params = append(params, "RET="+strings.ToUpper(string(d.Return)))
+	}
+	if d.EnvelopeID != "" {
+		params = append(params, "ENVID="+xtext(d.EnvelopeID))
+	}
+	return params
+}
+
+// rcptParams 返回 RCPT TO 命令的 NOTIFY 参数
+func (d *DeliveryNotification) rcptParams() []string {
+	return []string{"NOTIFY=" + strings.Join(d.notify(), ",")}
+}
+
+// sendmailArgs 返回 sendmail 的 -N、-R 和 -V 选项
+func (d *DeliveryNotification) sendmailArgs() []string {
+	args := []string{"-N", strings.ToLower(strings.Join(d.notify(), ","))}
+	if d.Return != "" {
+		args = append(args, "-R", strings.ToLower(string(d.Return)))
+	}
+	if d.EnvelopeID != "" {
+		args = append(args, "-V", d.EnvelopeID)
+	}
+	return args
+}
+
+// xtext 按 RFC 3461 第 4 节编码参数值："+"、"=" 和可打印字符以外的字节编码为 "+XX"
+func xtext(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < '!' || c > '~' || c == '+' || c == '=' {
+			fmt.Fprintf(&b, "+%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
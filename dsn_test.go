@@ -0,0 +1,122 @@
+package gomailer
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSMTPDeliveryNotification(t *testing.T) {
+	tests := []struct {
+		name           string
+		dsn            *DeliveryNotification
+		wantFromParams []string
+		wantRcptParams []string
+	}{
+		{"default notify", &DeliveryNotification{}, nil, []string{"NOTIFY=SUCCESS,FAILURE"}},
+		{"all fields", &DeliveryNotification{
+			Notify:     []DSNNotify{DSNNotifyFailure, DSNNotifyDelay},
+			Return:     DSNReturnHeaders,
+			EnvelopeID: "order+42=paid",
+		}, []string{"RET=HDRS", "ENVID=order+2B42+3Dpaid"}, []string{"NOTIFY=FAILURE,DELAY"}},
+		{"never", &DeliveryNotification{Notify: []DSNNotify{DSNNotifyNever}}, nil, []string{"NOTIFY=NEVER"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeSMTP(t, "DSN")
+
+			m := messageTo("a@example.com", "b@example.com")
+			m.DeliveryNotification = tt.dsn
+			if err := f.client().Send(m); err != nil {
+				t.Fatal(err)
+			}
+
+			txns := f.transactions()
+			if len(txns) != 1 {
+				t.Fatalf("transactions = %d, want 1", len(txns))
+			}
+			if got, want := strings.Join(txns[0].FromParams, " "), strings.Join(tt.wantFromParams, " "); got != want {
+				t.Errorf("MAIL FROM params = %q, want %q", txns[0].FromParams, tt.wantFromParams)
+			}
+			for i, params := range txns[0].RcptParams {
+				if !reflect.DeepEqual(params, tt.wantRcptParams) {
+					t.Errorf("RCPT TO %s params = %q, want %q", txns[0].Rcpts[i], params, tt.wantRcptParams)
+				}
+			}
+		})
+	}
+}
+
+func TestSMTPDeliveryNotificationWithoutExtension(t *testing.T) {
+	f := newFakeSMTP(t)
+
+	m := messageTo("a@example.com")
+	m.DeliveryNotification = &DeliveryNotification{Return: DSNReturnFull, EnvelopeID: "abc"}
+	if err := f.client().Send(m); err != nil {
+		t.Fatal(err)
+	}
+
+	// 服务器没有声明 DSN 时不能发送 DSN 参数
+	txns := f.transactions()
+	if len(txns) != 1 || len(txns[0].FromParams) != 0 || len(txns[0].RcptParams[0]) != 0 {
+		t.Errorf("transactions = %+v, want no DSN parameters", txns)
+	}
+}
+
+func TestSendmailDeliveryNotification(t *testing.T) {
+	argsFile, _ := installFakeSendmail(t)
+
+	m := messageTo("a@example.com")
+	m.DeliveryNotification = &DeliveryNotification{Notify: []DSNNotify{DSNNotifySuccess}, Return: DSNReturnHeaders, EnvelopeID: "abc"}
+	if err := (&Sendmail{}).Send(m); err != nil {
+		t.Fatal(err)
+	}
+
+	args, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"-i", "-f", "sender@example.com", "-N", "success", "-R", "hdrs", "-V", "abc", "a@example.com"}
+	if got := strings.Fields(string(args)); !reflect.DeepEqual(got, want) {
+		t.Errorf("args = %q, want %q", got, want)
+	}
+}
+
+func TestDeliveryNotificationValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		dsn       DeliveryNotification
+		wantField string
+	}{
+		{"valid", DeliveryNotification{Notify: []DSNNotify{"success", DSNNotifyDelay}, Return: "full"}, ""},
+		{"unknown notify", DeliveryNotification{Notify: []DSNNotify{"READ"}}, "deliveryNotification.notify[0]"},
+		{"never with others", DeliveryNotification{Notify: []DSNNotify{DSNNotifySuccess, DSNNotifyNever}}, "deliveryNotification.notify[1]"},
+		{"unknown return", DeliveryNotification{Return: "BODY"}, "deliveryNotification.return"},
+		{"envelope id with space", DeliveryNotification{EnvelopeID: "a b"}, "deliveryNotification.envelopeId"},
+		{"envelope id injection", DeliveryNotification{EnvelopeID: "a\r\nRCPT TO:<x@example.com>"}, "deliveryNotification.envelopeId"},
+		{"envelope id too long", DeliveryNotification{EnvelopeID: strings.Repeat("a", 101)}, "deliveryNotification.envelopeId"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := messageTo("a@example.com")
+			m.DeliveryNotification = &tt.dsn
+
+			err := m.Validate()
+			if tt.wantField == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var errs ValidationErrors
+			if !errors.As(err, &errs) || errs[0].Field != tt.wantField {
+				t.Errorf("errors = %v, want field %q", err, tt.wantField)
+			}
+		})
+	}
+}
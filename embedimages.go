@@ -0,0 +1,308 @@
+package gomailer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// EmbedImagesOptions 控制 Message.EmbedImages 嵌入哪些图片
+type EmbedImagesOptions struct {
+	// FS 本地图片所在的文件系统（如 embed.FS），img 的相对路径从中读取
+	FS fs.FS
+
+	// Dir 本地图片所在的目录，仅在 FS 为空时使用
+	// FS 和 Dir 都为空时只处理 data: URI
+	Dir string
+}
+
+// source 返回用于读取本地图片的文件系统，未配置时返回 nil
+func (o EmbedImagesOptions) source() fs.FS {
+	if o.FS != nil {
+		return o.FS
+	}
+	if o.Dir != "" {
+		return os.DirFS(o.Dir)
+	}
+	return nil
+}
+
+// EmbedImages 将 HTML 中引用的图片转换为内联附件
+//
+// 扫描 HTML 中所有 <img> 的 src 属性：
+//   - 媒体类型为 image/* 的 data: URI 会被解码为内联附件，其它 data: URI 保持不变
+//   - 本地路径（如 "images/logo.png"、"/images/logo.png"）在配置了 FS 或 Dir 时从中读取，
+//     超出其根目录的路径（如 "../secret.png"）保持不变
+//   - 其它地址（http:、https:、cid: 等）保持不变
+//
+// 每个图片生成唯一的 Content-ID（随机部分来自 crypto/rand）并添加到 Files，src 被改写为对应的 "cid:" 引用；
+// 同一个地址被多次引用时只嵌入一次。除被改写的 <img> 标签外，HTML 的其余部分保持原样
+//
+// 参数:
+//   - opts: 本地图片的来源
+//
+// 返回:
+//   - error: 解析 HTML、解码 data: URI 或找不到本地文件时返回错误，此时 Message 不会被修改
+func (m *Message) EmbedImages(opts EmbedImagesOptions) error {
+	if m.HTML == "" {
+		return nil
+	}
+
+	fsys := opts.source()
+	domain := "gomailer.local"
+	if at := strings.LastIndex(m.From.Address, "@"); at >= 0 && at < len(m.From.Address)-1 {
+		domain = m.From.Address[at+1:]
+	}
+
+	var files []Attachment
+	cids := map[string]string{} // src 或 "file:"+路径 -> Content-ID
+
+	// embed 返回 src 对应的 Content-ID，不需要嵌入时返回空字符串
+	embed := func(src string) (string, error) {
+		if cid, ok := cids[src]; ok {
+			return cid, nil
+		}
+
+		a, err := imageAttachment(src, fsys)
+		if err != nil || a == nil {
+			return "", err
+		}
+
+		// 不同写法的本地路径（如 "/img/a.png" 和 "img/a.png"）指向同一个文件
+		if a.Path != "" {
+			if cid, ok := cids["file:"+a.Path]; ok {
+				cids[src] = cid
+				return cid, nil
+			}
+		}
+
+		if a.Path == "" {
+			// data: URI 没有文件名，按顺序编号
+			a.Filename = fmt.Sprintf("image%d%s", len(files)+1, path.Ext(a.Filename))
+		}
+		random, err := randomIDPart(10)
+		if err != nil {
+			return "", err
+		}
+		a.ContentID = fmt.Sprintf("img%d.%s@%s", len(files)+1, random, domain)
+		a.Disposition = DispositionInline
+		files = append(files, *a)
+		cids[src] = a.ContentID
+		if a.Path != "" {
+			cids["file:"+a.Path] = a.ContentID
+		}
+
+		return a.ContentID, nil
+	}
+
+	var out strings.Builder
+	z := html.NewTokenizer(strings.NewReader(m.HTML))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if err := z.Err(); !errors.Is(err, io.EOF) {
+				return err
+			}
+			break
+		}
+
+		raw := z.Raw()
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			out.Write(raw)
+			continue
+		}
+
+		token := z.Token()
+		if token.Data != "img" {
+			out.Write(raw)
+			continue
+		}
+
+		rewritten := false
+		for i, attr := range token.Attr {
+			if attr.Namespace != "" || attr.Key != "src" {
+				continue
+			}
+
+			cid, err := embed(strings.TrimSpace(attr.Val))
+			if err != nil {
+				return fmt.Errorf("embed image %q: %w", truncateSrc(attr.Val), err)
+			}
+			if cid != "" {
+				token.Attr[i].Val = "cid:" + cid
+				rewritten = true
+			}
+		}
+
+		if rewritten {
+			out.WriteString(token.String())
+		} else {
+			out.Write(raw)
+		}
+	}
+
+	m.HTML = out.String()
+	m.Files = append(m.Files, files...)
+
+	return nil
+}
+
+// imageAttachment 根据 img 的 src 创建附件，不需要嵌入的地址返回 nil
+func imageAttachment(src string, fsys fs.FS) (*Attachment, error) {
+	if src == "" {
+		return nil, nil
+	}
+
+	if hasURLScheme(src) {
+		if !strings.HasPrefix(strings.ToLower(src), "data:") {
+			return nil, nil
+		}
+		return dataURIAttachment(src)
+	}
+
+	if fsys == nil || strings.HasPrefix(src, "//") {
+		return nil, nil
+	}
+
+	// 去掉查询参数和片段，并将路径转换为相对于文件系统根目录的形式
+	name := src
+	if i := strings.IndexAny(name, "?#"); i >= 0 {
+		name = name[:i]
+	}
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		name = unescaped
+	}
+	// 超出根目录的相对路径（如 "../secret.png"）不属于要嵌入的图片，保持不变
+	name = path.Clean(strings.TrimPrefix(name, "/"))
+	if name == ".." || strings.HasPrefix(name, "../") {
+		return nil, nil
+	}
+
+	if !fs.ValidPath(name) || name == "." {
+		return nil, fmt.Errorf("invalid path %q", name)
+	}
+
+	if _, err := fs.Stat(fsys, name); err != nil {
+		return nil, err
+	}
+
+	return &Attachment{Filename: path.Base(name), Path: name, FS: fsys}, nil
+}
+
+// dataURIAttachment 解码 RFC 2397 data: URI（"data:[<mediatype>][;base64],<data>"）
+//
+// 只嵌入图片：媒体类型不是 image/* 的 data: URI（包括省略媒体类型时默认的 text/plain）返回 nil
+func dataURIAttachment(uri string) (*Attachment, error) {
+	meta, payload, ok := strings.Cut(uri[len("data:"):], ",")
+	if !ok {
+		return nil, errors.New("malformed data URI")
+	}
+
+	isBase64 := false
+	if strings.HasSuffix(strings.ToLower(meta), ";base64") {
+		isBase64 = true
+		meta = meta[:len(meta)-len(";base64")]
+	}
+
+	if meta == "" {
+		return nil, nil
+	}
+	mediaType, _, err := mime.ParseMediaType(meta)
+	if err != nil {
+		return nil, fmt.Errorf("invalid media type %q", meta)
+	}
+	if !strings.HasPrefix(mediaType, "image/") {
+		return nil, nil
+	}
+	contentType := meta
+
+	var data []byte
+	if isBase64 {
+		// 兼容 HTML 中折行或带空格的 base64 内容
+		cleaned := strings.Map(func(r rune) rune {
+			if r == ' ' || r == '\t' || r == '\r' || r == '\n' {
+				return -1
+			}
+			return r
+		}, payload)
+
+		decoded, err := base64.StdEncoding.DecodeString(cleaned)
+		if err != nil {
+			decoded, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(cleaned, "="))
+			if err != nil {
+				return nil, err
+			}
+		}
+		data = decoded
+	} else {
+		decoded, err := url.PathUnescape(payload)
+		if err != nil {
+			return nil, err
+		}
+		data = []byte(decoded)
+	}
+
+	return &Attachment{
+		Filename:    "image" + imageExtension(mediaType),
+		ContentType: contentType,
+		Reader:      bytes.NewReader(data),
+		Size:        int64(len(data)),
+	}, nil
+}
+
+// imageExtension 返回 MIME 类型对应的常用扩展名（包含点号），未知时返回空字符串
+func imageExtension(mediaType string) string {
+	switch mediaType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	case "image/webp":
+		return ".webp"
+	case "image/svg+xml":
+		return ".svg"
+	}
+
+	if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+		return exts[0]
+	}
+
+	return ""
+}
+
+// hasURLScheme 检查地址是否以 URL 协议开头（如 "https:"、"data:"）
+func hasURLScheme(src string) bool {
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case i > 0 && (c >= '0' && c <= '9' || c == '+' || c == '-' || c == '.'):
+		case i > 0 && c == ':':
+			return true
+		default:
+			return false
+		}
+	}
+
+	return false
+}
+
+// truncateSrc 截断过长的地址（如 data: URI），用于错误信息
+func truncateSrc(src string) string {
+	if len(src) > 64 {
+		return src[:64] + "..."
+	}
+	return src
+}
@@ -0,0 +1,138 @@
+package gomailer
+
+import (
+	"io"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// pngPixel 是一个 1x1 的 PNG 图片
+const pngPixel = "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNkYPhfDwAChwGA60e6kgAAAABJRU5ErkJggg=="
+
+var contentIDPattern = regexp.MustCompile(`^img\d+\.[a-z2-7]{16}@example\.com$`)
+
+// embedMessage 创建发件人域名为 example.com 的 HTML 邮件
+func embedMessage(html string) *Message {
+	return &Message{From: mail.Address{Address: "sender@example.com"}, HTML: html}
+}
+
+func TestEmbedImagesDataURI(t *testing.T) {
+	m := embedMessage(`<p>logo</p><img alt="a" src="data:image/png;base64,` + pngPixel + `"><img src="data:image/png;base64,` + pngPixel + `">`)
+
+	if err := m.EmbedImages(EmbedImagesOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(m.Files) != 1 {
+		t.Fatalf("Files = %d, want 1 (identical data URIs are embedded once)", len(m.Files))
+	}
+	a := m.Files[0]
+	if !contentIDPattern.MatchString(a.ContentID) {
+		t.Errorf("ContentID = %q", a.ContentID)
+	}
+	if a.Disposition != DispositionInline || a.ContentType != "image/png" || a.Filename != "image1.png" {
+		t.Errorf("attachment = %+v", a)
+	}
+	if n := strings.Count(m.HTML, `src="cid:`+a.ContentID+`"`); n != 2 {
+		t.Errorf("HTML = %q, want both images rewritten", m.HTML)
+	}
+	if !strings.HasPrefix(m.HTML, `<p>logo</p><img alt="a" src="cid:`) {
+		t.Errorf("HTML = %q, want the rest of the document unchanged", m.HTML)
+	}
+
+	data, _ := io.ReadAll(a.Reader)
+	if len(data) == 0 || string(data[1:4]) != "PNG" {
+		t.Errorf("decoded data = %q", data)
+	}
+}
+
+func TestEmbedImagesContentIDsAreUnique(t *testing.T) {
+	seen := map[string]bool{}
+	for i := 0; i < 50; i++ {
+		m := embedMessage(`<img src="data:image/png;base64,` + pngPixel + `">`)
+		if err := m.EmbedImages(EmbedImagesOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		cid := m.Files[0].ContentID
+		if seen[cid] {
+			t.Fatalf("duplicate Content-ID %q", cid)
+		}
+		seen[cid] = true
+	}
+}
+
+func TestEmbedImagesLocalFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"images/logo.png":     {Data: []byte("logo")},
+		"images/my photo.jpg": {Data: []byte("photo")},
+	}
+
+	m := embedMessage(`<img src="images/logo.png"><img src="/images/logo.png?v=2"><img src="images/my%20photo.jpg">`)
+	if err := m.EmbedImages(EmbedImagesOptions{FS: fsys}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(m.Files) != 2 {
+		t.Fatalf("Files = %+v, want 2", m.Files)
+	}
+	if m.Files[0].Path != "images/logo.png" || m.Files[0].Filename != "logo.png" || m.Files[1].Filename != "my photo.jpg" {
+		t.Errorf("Files = %+v", m.Files)
+	}
+	if n := strings.Count(m.HTML, "cid:"+m.Files[0].ContentID); n != 2 {
+		t.Errorf("HTML = %q, want both logo references rewritten", m.HTML)
+	}
+	if !strings.Contains(m.HTML, "cid:"+m.Files[1].ContentID) {
+		t.Errorf("HTML = %q", m.HTML)
+	}
+}
+
+func TestEmbedImagesLeavesOtherSourcesAlone(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "public")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "secret.png"), []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []string{
+		`https://example.com/logo.png`,
+		`//cdn.example.com/logo.png`,
+		`cid:logo@example.com`,
+		`../secret.png`,
+		`/../secret.png`,
+		`images/../../secret.png`,
+		`data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==`,
+		`data:,hello`,
+	}
+
+	for _, src := range tests {
+		html := `<img src="` + src + `">`
+		m := embedMessage(html)
+		if err := m.EmbedImages(EmbedImagesOptions{Dir: dir}); err != nil {
+			t.Errorf("%s: %v", src, err)
+			continue
+		}
+		if m.HTML != html || len(m.Files) != 0 {
+			t.Errorf("%s: HTML = %q, Files = %d, want unchanged", src, m.HTML, len(m.Files))
+		}
+	}
+}
+
+func TestEmbedImagesMissingFile(t *testing.T) {
+	html := `<img src="data:image/png;base64,` + pngPixel + `"><img src="missing.png">`
+	m := embedMessage(html)
+
+	if err := m.EmbedImages(EmbedImagesOptions{FS: fstest.MapFS{}}); err == nil {
+		t.Fatal("expected error for missing file")
+	}
+	if m.HTML != html || len(m.Files) != 0 {
+		t.Error("message must not be modified on error")
+	}
+}
@@ -0,0 +1,134 @@
+package gomailer
+
+import (
+	"encoding/base64"
+	"io"
+	"mime/quotedprintable"
+	"strings"
+)
+
+// 内容传输编码（Content-Transfer-Encoding）
+const (
+	encoding7Bit            = "7bit"
+	encoding8Bit            = "8bit"
+	encodingQuotedPrintable = "quoted-printable"
+	encodingBase64          = "base64"
+)
+
+// textAnalysis 是对文本内容的统计，用于选择传输编码
+type textAnalysis struct {
+	// maxLine 最长一行的字节数（不含 CRLF）
+	maxLine int
+
+	// nonASCII 大于 0x7F 的字节数
+	nonASCII int
+
+	// nul 是否包含 NUL 字节（7bit 和 8bit 都不允许）
+	nul bool
+}
+
+// analyzeText 统计已经规范为 CRLF 换行的文本内容
+func analyzeText(s string) textAnalysis {
+	var a textAnalysis
+
+	line := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\r' && i+1 < len(s) && s[i+1] == '\n':
+			a.maxLine = max(a.maxLine, line)
+			line = 0
+			i++
+			continue
+		case c == 0:
+			a.nul = true
+		case c >= 0x80:
+			a.nonASCII++
+		}
+		line++
+	}
+	a.maxLine = max(a.maxLine, line)
+
+	return a
+}
+
+// chooseTextEncoding 为文本内容选择传输编码
+//
+// 返回在任何 SMTP 服务器上都安全的编码，以及服务器支持 8BITMIME 时能否改用 8bit：
+//   - 纯 ASCII 且每行不超过 998 个字节：7bit
+//   - 非 ASCII 字节超过 20%（如中文、日文）：base64，体积比 quoted-printable 小
+//   - 其它情况（少量非 ASCII 字节或超长的行）：quoted-printable
+func chooseTextEncoding(s string) (encoding string, eightBit bool) {
+	a := analyzeText(s)
+
+	// RFC 5322 2.1.1 的行长度限制同样适用于正文
+	fits := !a.nul && a.maxLine <= headerHardLimit
+	if fits && a.nonASCII == 0 {
+		return encoding7Bit, false
+	}
+
+	if a.nonASCII*5 > len(s) {
+		return encodingBase64, fits
+	}
+
+	return encodingQuotedPrintable, fits
+}
+
+// normalizeNewlines 将 LF 和单独的 CR 统一转换为 CRLF（RFC 5322 要求的换行）
+func normalizeNewlines(s string) string {
+	if !strings.ContainsAny(s, "\r\n") {
+		return s
+	}
+
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+	return strings.ReplaceAll(s, "\n", "\r\n")
+}
+
+// encodedTextPart 创建一个文本实体，传输编码根据内容自动选择
+//
+// 可以使用 8bit 的实体会记录 to8bit，在 SMTP 服务器声明 8BITMIME 时切换
+func encodedTextPart(contentType, content string) *mimePart {
+	content = normalizeNewlines(content)
+	encoding, eightBit := chooseTextEncoding(content)
+
+	p := &mimePart{body: encodedBody(encoding, content)}
+	p.header.add("Content-Type", contentType)
+	p.header.add("Content-Transfer-Encoding", encoding)
+
+	if eightBit {
+		p.to8bit = func() {
+			p.header.set("Content-Transfer-Encoding", encoding8Bit)
+			p.body = encodedBody(encoding8Bit, content)
+		}
+	}
+
+	return p
+}
+
+// encodedBody 返回按指定传输编码写出 content 的实体内容函数
+func encodedBody(encoding, content string) func(w io.Writer) error {
+	switch encoding {
+	case encodingBase64:
+		return func(w io.Writer) error {
+			encoder := base64.NewEncoder(base64.StdEncoding, &lineWrapper{w: w, max: 76})
+			if _, err := io.WriteString(encoder, content); err != nil {
+				return err
+			}
+			return encoder.Close()
+		}
+	case encodingQuotedPrintable:
+		return func(w io.Writer) error {
+			qp := quotedprintable.NewWriter(w)
+			if _, err := io.WriteString(qp, content); err != nil {
+				return err
+			}
+			return qp.Close()
+		}
+	default:
+		// 7bit 和 8bit 原样写出
+		return func(w io.Writer) error {
+			_, err := io.WriteString(w, content)
+			return err
+		}
+	}
+}
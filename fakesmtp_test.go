@@ -0,0 +1,204 @@
+package gomailer
+
+import (
+	"bufio"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeTransaction 是假 SMTP 服务器收到的一次完整事务
+type fakeTransaction struct {
+	From       string
+	FromParams []string
+	Rcpts      []string
+	RcptParams [][]string
+	Data       string
+}
+
+// fakeSMTP 是一个用于测试的最小 SMTP 服务器
+type fakeSMTP struct {
+	// Extensions EHLO 时声明的扩展（如 "SIZE 1000"、"8BITMIME"）
+	Extensions []string
+
+	// Reject 被拒绝的收件人及响应（如 "550 5.1.1 no such user"）
+	Reject map[string]string
+
+	// MaxRcpt 单个事务允许的收件人数，超出时返回 452，0 表示不限制
+	MaxRcpt int
+
+	// DataReply DATA 结束后的响应，为空时返回 250
+	DataReply string
+
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu          sync.Mutex
+	Txns        []fakeTransaction
+	Connections int
+	Resets      int
+}
+
+// newFakeSMTP 启动假 SMTP 服务器，测试结束时自动关闭
+func newFakeSMTP(t testing.TB, extensions ...string) *fakeSMTP {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &fakeSMTP{Extensions: extensions, listener: l}
+	f.wg.Add(1)
+	go f.serve()
+
+	t.Cleanup(func() {
+		l.Close()
+		f.wg.Wait()
+	})
+
+	return f
+}
+
+// client 返回连接到假服务器的 SMTPClient
+func (f *fakeSMTP) client() *SMTPClient {
+	addr := f.listener.Addr().(*net.TCPAddr)
+	return &SMTPClient{Host: "127.0.0.1", Port: addr.Port}
+}
+
+// transactions 返回已完成的事务
+func (f *fakeSMTP) transactions() []fakeTransaction {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]fakeTransaction(nil), f.Txns...)
+}
+
+// resets 返回收到的 RSET 命令数
+func (f *fakeSMTP) resets() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.Resets
+}
+
+// connections 返回已接受的连接数
+func (f *fakeSMTP) connections() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.Connections
+}
+
+func (f *fakeSMTP) serve() {
+	defer f.wg.Done()
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		f.mu.Lock()
+		f.Connections++
+		f.mu.Unlock()
+
+		f.wg.Add(1)
+		go func() {
+			defer f.wg.Done()
+			defer conn.Close()
+			f.handle(textproto.NewConn(conn))
+		}()
+	}
+}
+
+func (f *fakeSMTP) handle(c *textproto.Conn) {
+	var txn fakeTransaction
+	inMail := false
+
+	c.PrintfLine("220 fake ESMTP")
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return
+		}
+
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			lines := append([]string{"fake"}, f.Extensions...)
+			for i, l := range lines {
+				sep := "-"
+				if i == len(lines)-1 {
+					sep = " "
+				}
+				c.PrintfLine("250%s%s", sep, l)
+			}
+		case "HELO", "NOOP":
+			c.PrintfLine("250 ok")
+		case "RSET":
+			f.mu.Lock()
+			f.Resets++
+			f.mu.Unlock()
+			txn, inMail = fakeTransaction{}, false
+			c.PrintfLine("250 ok")
+		case "MAIL":
+			fields := strings.Fields(strings.TrimPrefix(arg, "FROM:"))
+			txn = fakeTransaction{From: strings.Trim(fields[0], "<>"), FromParams: fields[1:]}
+			inMail = true
+			c.PrintfLine("250 ok")
+		case "RCPT":
+			fields := strings.Fields(strings.TrimPrefix(arg, "TO:"))
+			rcpt := strings.Trim(fields[0], "<>")
+			if reply, ok := f.Reject[rcpt]; ok {
+				c.PrintfLine("%s", reply)
+				continue
+			}
+			if !inMail {
+				c.PrintfLine("503 need MAIL first")
+				continue
+			}
+			if f.MaxRcpt > 0 && len(txn.Rcpts) >= f.MaxRcpt {
+				c.PrintfLine("452 4.5.3 too many recipients")
+				continue
+			}
+			txn.Rcpts = append(txn.Rcpts, rcpt)
+			txn.RcptParams = append(txn.RcptParams, fields[1:])
+			c.PrintfLine("250 ok")
+		case "DATA":
+			if len(txn.Rcpts) == 0 {
+				c.PrintfLine("554 no valid recipients")
+				continue
+			}
+			c.PrintfLine("354 go ahead")
+			data, err := c.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			txn.Data = string(data)
+			if f.DataReply != "" {
+				c.PrintfLine("%s", f.DataReply)
+			} else {
+				f.mu.Lock()
+				f.Txns = append(f.Txns, txn)
+				f.mu.Unlock()
+				c.PrintfLine("250 queued")
+			}
+			txn, inMail = fakeTransaction{}, false
+		case "QUIT":
+			c.PrintfLine("221 bye")
+			return
+		default:
+			c.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+// readHeader 返回渲染后邮件的头部（第一个空行之前的部分）
+func readHeader(t testing.TB, data string) textproto.MIMEHeader {
+	t.Helper()
+
+	h, err := textproto.NewReader(bufio.NewReader(strings.NewReader(data))).ReadMIMEHeader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
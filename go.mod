@@ -1,10 +1,17 @@
 module github.com/yourusername/gomailer
 
-go 1.23
+go 1.24.0
 
 require (
-	github.com/domodwyer/mailyak/v3 v3.6.2
+	github.com/ProtonMail/go-crypto v1.3.0
 	github.com/gabriel-vasile/mimetype v1.4.10
+	github.com/smallstep/pkcs7 v0.2.3
 	golang.org/x/net v0.46.0
+	golang.org/x/text v0.30.0
 )
 
+require (
+	github.com/cloudflare/circl v1.6.1 // indirect
+	golang.org/x/crypto v0.43.0 // indirect
+	golang.org/x/sys v0.37.0 // indirect
+)
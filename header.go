@@ -0,0 +1,221 @@
+package gomailer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/mail"
+	"strings"
+)
+
+const (
+	// headerSoftLimit 邮件头行的推荐最大长度（RFC 5322 2.1.1，不含 CRLF）
+	headerSoftLimit = 78
+
+	// headerHardLimit 邮件头行的绝对最大长度（RFC 5322 2.1.1，不含 CRLF）
+	headerHardLimit = 998
+)
+
+// ErrHeaderInjection 表示邮件头的名称或值中包含可能导致头部注入的内容
+//
+// 校验或渲染邮件头时返回的 *HeaderInjectionError 满足 errors.Is(err, ErrHeaderInjection)
+var ErrHeaderInjection = errors.New("header injection detected")
+
+// HeaderInjectionError 描述了一个不安全的邮件头字段
+type HeaderInjectionError struct {
+	// Field 邮件头字段名
+	Field string
+
+	// Reason 拒绝原因
+	Reason string
+}
+
+// Error 实现 error 接口
+func (e *HeaderInjectionError) Error() string {
+	return fmt.Sprintf("header %q: %s", e.Field, e.Reason)
+}
+
+// Is 使 errors.Is(err, ErrHeaderInjection) 返回 true
+func (e *HeaderInjectionError) Is(target error) bool {
+	return target == ErrHeaderInjection
+}
+
+// checkHeaderName 检查邮件头字段名是否符合 RFC 5322（可打印 ASCII，不含冒号和空白）
+func checkHeaderName(name string) error {
+	if !validHeaderName(name) {
+		return &HeaderInjectionError{Field: name, Reason: "invalid header field name"}
+	}
+	return nil
+}
+
+// checkHeaderValue 检查邮件头的值中是否包含 CR、LF 或其它控制字符（制表符除外）
+func checkHeaderValue(name, value string) error {
+	for _, r := range value {
+		switch {
+		case r == '\r' || r == '\n':
+			return &HeaderInjectionError{Field: name, Reason: "value must not contain CR or LF"}
+		case r < 32 && r != '\t', r == 127:
+			return &HeaderInjectionError{Field: name, Reason: fmt.Sprintf("value must not contain control character %U", r)}
+		}
+	}
+	return nil
+}
+
+// checkAddressHeader 检查地址中的姓名和邮箱是否可以安全地写入邮件头
+func checkAddressHeader(name string, addrs ...mail.Address) error {
+	for _, addr := range addrs {
+		if err := checkHeaderValue(name, addr.Name); err != nil {
+			return err
+		}
+		if err := checkHeaderValue(name, addr.Address); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeHeaderText 将非结构化的邮件头文本（如 Subject）编码为 RFC 2047 编码字
+//
+// 纯 ASCII 文本保持原样，其它文本使用 UTF-8 Q 编码
+// 过长的编码结果会被拆分为以空格分隔的多个编码字，便于后续折行
+func encodeHeaderText(value string) string {
+	return mime.QEncoding.Encode("utf-8", value)
+}
+
+// formatAddress 将单个地址格式化为邮件头的值
+func formatAddress(addr mail.Address) string {
+	if addr.Name == "" {
+		return addr.Address
+	}
+	return addr.String()
+}
+
+// foldHeader 按 RFC 5322 将一个邮件头字段折行，返回以 CRLF 结尾的完整字段
+//
+// 折行只发生在已有的空白处（包括字段名冒号后的空格），每行尽量不超过 78 个字符；
+// 如果某一段无法在 998 个字符内折行，返回错误
+func foldHeader(name, value string) (string, error) {
+	var b strings.Builder
+
+	line := name + ":"
+	rest := ""
+	if value != "" {
+		rest = " " + value
+	}
+
+	// 首行已经包含字段名，因此冒号后的空格也可以作为折行位置
+	first := true
+	for len(line)+len(rest) > headerSoftLimit {
+		cut := foldPoint(rest, headerSoftLimit-len(line), first)
+		first = false
+		if cut < 0 {
+			break
+		}
+
+		line += rest[:cut]
+		if len(line) > headerHardLimit {
+			return "", fmt.Errorf("header %q: line exceeds %d characters", name, headerHardLimit)
+		}
+
+		b.WriteString(line)
+		b.WriteString("\r\n")
+
+		// 续行以原有的空白字符开头
+		line = ""
+		rest = rest[cut:]
+	}
+
+	line += rest
+	if len(line) > headerHardLimit {
+		return "", fmt.Errorf("header %q: line exceeds %d characters", name, headerHardLimit)
+	}
+
+	b.WriteString(line)
+	b.WriteString("\r\n")
+
+	return b.String(), nil
+}
+
+// foldPoint 返回 s 中适合折行的位置（空白字符的下标）
+//
+// 优先选择不超过 limit 的最后一个空白，没有时退而选择其后的第一个空白；
+// 除非 leading 为 true，折行位置之前必须包含非空白内容，避免产生只有空白的行。
+// 找不到时返回 -1
+func foldPoint(s string, limit int, leading bool) int {
+	cut := -1
+	seenText := leading
+
+	for i := 0; i < len(s); i++ {
+		if s[i] != ' ' && s[i] != '\t' {
+			seenText = true
+			continue
+		}
+		if !seenText {
+			continue
+		}
+		if i > limit && cut >= 0 {
+			break
+		}
+		cut = i
+		if i > limit {
+			break
+		}
+	}
+
+	return cut
+}
+
+// -------------------------------------------------------------------
+// 邮件头集合
+// -------------------------------------------------------------------
+
+// headerField 是一个已编码（纯 ASCII）但尚未折行的邮件头字段
+type headerField struct {
+	name  string
+	value string
+}
+
+// mailHeader 是一个保持字段顺序的邮件头集合
+type mailHeader struct {
+	fields []headerField
+}
+
+// add 追加一个字段
+func (h *mailHeader) add(name, value string) {
+	h.fields = append(h.fields, headerField{name: name, value: value})
+}
+
+// set 设置字段的值，已存在的同名字段（不区分大小写）会被替换
+func (h *mailHeader) set(name, value string) {
+	for i, f := range h.fields {
+		if strings.EqualFold(f.name, name) {
+			h.fields[i] = headerField{name: name, value: value}
+			return
+		}
+	}
+	h.add(name, value)
+}
+
+// writeTo 校验、折行并写入所有字段
+func (h *mailHeader) writeTo(w io.Writer) error {
+	for _, f := range h.fields {
+		if err := checkHeaderName(f.name); err != nil {
+			return err
+		}
+		if err := checkHeaderValue(f.name, f.value); err != nil {
+			return err
+		}
+
+		line, err := foldHeader(f.name, f.value)
+		if err != nil {
+			return err
+		}
+
+		if _, err := io.WriteString(w, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
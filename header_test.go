@@ -0,0 +1,124 @@
+package gomailer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckHeaderValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{"plain", "Hello world", false},
+		{"tab", "a\tb", false},
+		{"unicode", "你好，世界", false},
+		{"empty", "", false},
+		{"CRLF injection", "hi\r\nBcc: victim@example.com", true},
+		{"bare LF", "hi\nBcc: victim@example.com", true},
+		{"bare CR", "hi\rthere", true},
+		{"NUL", "a\x00b", true},
+		{"escape", "a\x1bb", true},
+		{"DEL", "a\x7fb", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkHeaderValue("Subject", tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrHeaderInjection) {
+				t.Errorf("err = %v, want ErrHeaderInjection", err)
+			}
+		})
+	}
+}
+
+func TestCheckHeaderName(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"X-Campaign", false},
+		{"X_Custom.1", false},
+		{"", true},
+		{"X Campaign", true},
+		{"X-Campaign:", true},
+		{"X-\r\nBcc", true},
+		{"X-Ünicode", true},
+	}
+
+	for _, tt := range tests {
+		if err := checkHeaderName(tt.name); (err != nil) != tt.wantErr {
+			t.Errorf("checkHeaderName(%q) = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestFoldHeader(t *testing.T) {
+	long := strings.TrimSpace(strings.Repeat("word ", 40))
+
+	tests := []struct {
+		name    string
+		field   string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"short", "Subject", "Hello", "Subject: Hello\r\n", false},
+		{"empty", "Subject", "", "Subject:\r\n", false},
+		{"exactly 78", "Subject", strings.Repeat("a", 69), "Subject: " + strings.Repeat("a", 69) + "\r\n", false},
+		{"folds at whitespace", "Subject", long, "", false},
+		{"long word on first line moves to continuation", "X-Token", strings.Repeat("t", 100),
+			"X-Token:\r\n " + strings.Repeat("t", 100) + "\r\n", false},
+		{"unfoldable over 998", "X-Token", strings.Repeat("t", 1000), "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := foldHeader(tt.field, tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.want != "" && got != tt.want {
+				t.Errorf("foldHeader = %q, want %q", got, tt.want)
+			}
+
+			if !strings.HasSuffix(got, "\r\n") {
+				t.Fatalf("field must end with CRLF: %q", got)
+			}
+			lines := strings.Split(strings.TrimSuffix(got, "\r\n"), "\r\n")
+			for i, line := range lines {
+				if len(line) > headerSoftLimit && strings.ContainsAny(strings.TrimSpace(line), " \t") {
+					t.Errorf("line %d is %d characters but could have been folded: %q", i, len(line), line)
+				}
+				if i > 0 && !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
+					t.Errorf("continuation line %d must start with whitespace: %q", i, line)
+				}
+			}
+
+			// 去掉折行后与原值相同
+			unfolded := strings.ReplaceAll(got, "\r\n", "")
+			if want := tt.field + ":" + prefixSpace(tt.value); unfolded != want {
+				t.Errorf("unfolded = %q, want %q", unfolded, want)
+			}
+		})
+	}
+}
+
+func prefixSpace(s string) string {
+	if s == "" {
+		return ""
+	}
+	return " " + s
+}
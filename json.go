@@ -0,0 +1,224 @@
+package gomailer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MessageSchemaVersion 是 Message 序列化为 JSON 时写入的 schemaVersion
+//
+// 格式发生不兼容的变化时递增；UnmarshalJSON 拒绝高于此版本的数据，
+// 避免旧版本的程序静默丢弃新格式中的内容
+const MessageSchemaVersion = 1
+
+// messageFields 与 Message 字段相同，但没有 MarshalJSON/UnmarshalJSON 方法
+type messageFields Message
+
+// messageJSON 是 Message 的 JSON 格式，附件字段覆盖 Message 中的同名字段
+type messageJSON struct {
+	SchemaVersion int `json:"schemaVersion"`
+
+	*messageFields
+
+	Files             []attachmentJSON  `json:"files"`
+	Attachments       map[string][]byte `json:"attachments"`
+	InlineAttachments map[string][]byte `json:"inlineAttachments"`
+}
+
+// attachmentFields 与 Attachment 字段相同
+type attachmentFields Attachment
+
+// attachmentJSON 是 Attachment 的 JSON 格式：元数据加上 base64 编码的内容或文件路径
+type attachmentJSON struct {
+	attachmentFields
+
+	// Content 附件内容，编码为 base64；为空时附件引用 Path 指向的本地文件
+	Content []byte `json:"content,omitempty"`
+}
+
+// MarshalJSON 实现 json.Marshaler 接口，用于通过任务队列等方式传递邮件
+//
+// 附件的序列化方式：
+//   - 只设置了 Path 的附件序列化为文件引用，不读取文件内容，
+//     反序列化的一方需要能够访问同一路径
+//   - 其它附件（Reader、ReaderAt、FS 中的文件，以及 Attachments/InlineAttachments 映射）
+//     读取内容并编码为 base64
+//
+// 序列化不会修改邮件：实现了 io.Seeker 的 Reader 读取后回到原来的位置，
+// 只能读取一次的 Reader 返回错误，需要先调用 Clone（会将其替换为内存中的副本）再序列化
+//
+// 返回:
+//   - []byte: JSON 数据，包含 schemaVersion 字段
+//   - error: 读取附件内容失败或 Reader 只能读取一次时返回错误
+func (m Message) MarshalJSON() ([]byte, error) {
+	out := messageJSON{
+		SchemaVersion: MessageSchemaVersion,
+		messageFields: (*messageFields)(&m),
+	}
+
+	if m.Files != nil {
+		out.Files = make([]attachmentJSON, len(m.Files))
+		for i := range m.Files {
+			a, err := marshalAttachment(m.Files[i])
+			if err != nil {
+				return nil, err
+			}
+			out.Files[i] = a
+		}
+	}
+
+	var err error
+	if out.Attachments, err = marshalAttachmentMap(m.Attachments); err != nil {
+		return nil, err
+	}
+	if out.InlineAttachments, err = marshalAttachmentMap(m.InlineAttachments); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON 实现 json.Unmarshaler 接口，解析 MarshalJSON 生成的数据
+//
+// 附件内容还原为 *bytes.Reader，文件引用还原为 Path。没有 schemaVersion 字段的数据
+// 按当前版本处理，高于 MessageSchemaVersion 的版本返回错误
+//
+// 参数:
+//   - data: JSON 数据
+//
+// 返回:
+//   - error: JSON 格式错误或版本不受支持时返回错误
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var version struct {
+		SchemaVersion int `json:"schemaVersion"`
+	}
+	if err := json.Unmarshal(data, &version); err != nil {
+		return err
+	}
+
+	if version.SchemaVersion < 0 || version.SchemaVersion > MessageSchemaVersion {
+		return fmt.Errorf("unsupported message schema version %d (supported up to %d)", version.SchemaVersion, MessageSchemaVersion)
+	}
+
+	in := messageJSON{messageFields: (*messageFields)(m)}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	if in.Files != nil {
+		m.Files = make([]Attachment, len(in.Files))
+		for i, a := range in.Files {
+			m.Files[i] = Attachment(a.attachmentFields)
+			if a.Content != nil {
+				m.Files[i].Reader = bytes.NewReader(a.Content)
+			}
+		}
+	}
+
+	if in.Attachments != nil {
+		m.Attachments = unmarshalAttachmentMap(in.Attachments)
+	}
+	if in.InlineAttachments != nil {
+		m.InlineAttachments = unmarshalAttachmentMap(in.InlineAttachments)
+	}
+
+	return nil
+}
+
+// marshalAttachment 将附件转换为 JSON 格式，内容不是本地文件时读入内存
+func marshalAttachment(a Attachment) (attachmentJSON, error) {
+	out := attachmentJSON{attachmentFields: attachmentFields(a)}
+
+	// 本地文件只记录路径
+	if a.Reader == nil && a.ReaderAt == nil && a.FS == nil {
+		return out, nil
+	}
+
+	var data []byte
+	var err error
+	if a.Reader != nil {
+		data, err = readContent(a.Reader)
+	} else {
+		data, err = readAttachment(&a)
+	}
+	if err != nil {
+		return out, fmt.Errorf("attachment %q: %w", a.name(), err)
+	}
+
+	// 内容已经内联，FS 中的文件名只保留为附件的文件名
+	out.Filename = a.name()
+	out.Path = ""
+	out.Content = data
+
+	return out, nil
+}
+
+// readAttachment 读取 ReaderAt 或 FS 中的附件内容
+func readAttachment(a *Attachment) ([]byte, error) {
+	r, closeFn, err := a.open()
+	if err != nil {
+		return nil, err
+	}
+	defer closeFn()
+
+	return io.ReadAll(r)
+}
+
+// marshalAttachmentMap 读取 Attachments/InlineAttachments 映射中的附件内容
+func marshalAttachmentMap(files map[string]io.Reader) (map[string][]byte, error) {
+	if files == nil {
+		return nil, nil
+	}
+
+	out := make(map[string][]byte, len(files))
+	for name, r := range files {
+		if r == nil {
+			continue
+		}
+
+		data, err := readContent(r)
+		if err != nil {
+			return nil, fmt.Errorf("attachment %q: %w", name, err)
+		}
+		out[name] = data
+	}
+
+	return out, nil
+}
+
+// unmarshalAttachmentMap 将附件内容还原为 Reader 映射
+func unmarshalAttachmentMap(files map[string][]byte) map[string]io.Reader {
+	out := make(map[string]io.Reader, len(files))
+	for name, data := range files {
+		out[name] = bytes.NewReader(data)
+	}
+	return out
+}
+
+// errReaderNotReplayable 表示 Reader 只能读取一次，序列化会使原邮件无法再发送
+var errReaderNotReplayable = errors.New("reader can only be read once; call Clone before marshaling")
+
+// readContent 读取 r 的全部内容，读取后回到原来的位置
+//
+// 没有实现 io.Seeker 的 Reader 读取后无法恢复，返回错误而不是读取它
+func readContent(r io.Reader) ([]byte, error) {
+	s, ok := r.(io.Seeker)
+	if !ok {
+		return nil, errReaderNotReplayable
+	}
+
+	offset, err := s.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(r)
+	if _, serr := s.Seek(offset, io.SeekStart); err == nil {
+		err = serr
+	}
+
+	return data, err
+}
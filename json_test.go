@@ -0,0 +1,127 @@
+package gomailer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+// onceReader 是只能读取一次的 Reader（不实现 io.Seeker）
+type onceReader struct {
+	r io.Reader
+}
+
+func (o *onceReader) Read(p []byte) (int, error) {
+	return o.r.Read(p)
+}
+
+func TestMessageMarshalJSONByValue(t *testing.T) {
+	file := bytes.NewReader([]byte("report"))
+	notes := strings.NewReader("notes")
+
+	m := Message{
+		From:        mail.Address{Address: "sender@example.com"},
+		To:          []mail.Address{{Address: "to@example.com"}},
+		Subject:     "月度报表",
+		Text:        "see attachments",
+		Files:       []Attachment{{Filename: "report.csv", Reader: file}, {Path: "/data/contract.pdf"}},
+		Attachments: map[string]io.Reader{"notes.txt": notes},
+	}
+
+	// 值和嵌入在其它结构体中的 Message 都使用 MarshalJSON
+	type job struct {
+		Queue   string  `json:"queue"`
+		Message Message `json:"message"`
+	}
+
+	byValue, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byPointer, err := json.Marshal(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(byValue, byPointer) {
+		t.Errorf("value and pointer encodings differ:\n%s\n%s", byValue, byPointer)
+	}
+	if !bytes.Contains(byValue, []byte(`"schemaVersion":1`)) {
+		t.Errorf("missing schemaVersion: %s", byValue)
+	}
+
+	data, err := json.Marshal(job{Queue: "mail", Message: m})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 序列化不修改原邮件
+	if m.Files[0].Reader != file || m.Attachments["notes.txt"] != notes {
+		t.Error("MarshalJSON must not replace attachment readers")
+	}
+	if file.Len() != len("report") || notes.Len() != len("notes") {
+		t.Error("MarshalJSON must restore reader positions")
+	}
+
+	var decoded job
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	got := decoded.Message
+	if got.Subject != m.Subject || len(got.Files) != 2 || got.Files[1].Path != "/data/contract.pdf" {
+		t.Fatalf("decoded = %+v", got)
+	}
+	if content, _ := io.ReadAll(got.Files[0].Reader); string(content) != "report" {
+		t.Errorf("file content = %q", content)
+	}
+	if content, _ := io.ReadAll(got.Attachments["notes.txt"]); string(content) != "notes" {
+		t.Errorf("notes content = %q", content)
+	}
+}
+
+func TestMessageMarshalJSONRejectsOneShotReaders(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  func(r io.Reader) *Message
+	}{
+		{"files", func(r io.Reader) *Message {
+			return &Message{Files: []Attachment{{Filename: "upload.bin", Reader: r}}}
+		}},
+		{"attachments", func(r io.Reader) *Message {
+			return &Message{Attachments: map[string]io.Reader{"upload.bin": r}}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &onceReader{r: strings.NewReader("upload")}
+			m := tt.msg(r)
+
+			if _, err := json.Marshal(m); !errors.Is(err, errReaderNotReplayable) {
+				t.Fatalf("err = %v, want errReaderNotReplayable", err)
+			}
+			if content, _ := io.ReadAll(r); string(content) != "upload" {
+				t.Fatalf("reader was consumed: %q left", content)
+			}
+
+			// Clone 之后可以序列化
+			r = &onceReader{r: strings.NewReader("upload")}
+			m = tt.msg(r)
+			c, err := m.Clone()
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := json.Marshal(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(data), `"dXBsb2Fk"`) {
+				t.Errorf("content missing from %s", data)
+			}
+		})
+	}
+}
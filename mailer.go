@@ -4,8 +4,12 @@ package gomailer
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"net/mail"
+	"strings"
 
 	"github.com/gabriel-vasile/mimetype"
 )
@@ -25,22 +29,70 @@ type Message struct {
 	// Cc 抄送收件人列表（其他收件人可以看到此列表）
 	Cc []mail.Address `json:"cc"`
 
+	// ReplyTo 回复地址列表（收件人点击"回复"时使用的地址）
+	ReplyTo []mail.Address `json:"replyTo"`
+
+	// Sender 实际发送者地址，仅在与 From 不同时需要设置（例如代他人发送）
+	Sender mail.Address `json:"sender"`
+
+	// ReadReceiptTo 接收阅读回执的地址，生成 Disposition-Notification-To 邮件头（RFC 8098）
+	// 是否发送回执由收件人的客户端决定，收到的回执可以通过 ParseMDN 解析
+	ReadReceiptTo []mail.Address `json:"readReceiptTo,omitempty"`
+
+	// DeliveryNotification 可选的投递状态通知（DSN，RFC 3461）请求，由邮件服务器在投递时生成送达回执，
+	// 通知发送到信封发件人；只有声明了 DSN 扩展的 SMTP 服务器和支持 -N 选项的 sendmail 会处理
+	DeliveryNotification *DeliveryNotification `json:"deliveryNotification,omitempty"`
+
+	// MessageID 邮件的 Message-ID（尖括号可省略），为空时发送时自动生成
+	// 可以通过 GenerateMessageID 预先生成，用于关联退信等后续事件
+	MessageID string `json:"messageId,omitempty"`
+
+	// InReplyTo 被回复邮件的 Message-ID（尖括号可省略）
+	InReplyTo string `json:"inReplyTo"`
+
+	// References 邮件会话中此前各邮件的 Message-ID 列表（尖括号可省略）
+	References []string `json:"references"`
+
 	// Subject 邮件主题
 	Subject string `json:"subject"`
 
+	// Priority 邮件优先级（PriorityLow、PriorityNormal、PriorityHigh、PriorityUrgent）
+	// 设置后生成 X-Priority、Importance 和 Priority 邮件头，为空时不生成
+	Priority Priority `json:"priority,omitempty"`
+
 	// HTML HTML格式的邮件正文
 	HTML string `json:"html"`
 
 	// Text 纯文本格式的邮件正文（如果不提供，会自动从HTML转换）
 	Text string `json:"text"`
 
+	// Charset 正文和邮件头使用的字符集（如 "ISO-2022-JP"、"GB18030"），为空时使用 UTF-8
+	// 内容会从 UTF-8 转码，包含字符集无法表示的字符时发送失败
+	Charset string `json:"charset,omitempty"`
+
+	// TextCharset 纯文本正文使用的字符集，为空时使用 Charset
+	TextCharset string `json:"textCharset,omitempty"`
+
+	// HTMLCharset HTML 正文使用的字符集，为空时使用 Charset
+	// HTML 中的 <meta charset> 声明需要与之保持一致
+	HTMLCharset string `json:"htmlCharset,omitempty"`
+
 	// Headers 自定义邮件头部信息
 	Headers map[string]string `json:"headers"`
 
-	// Attachments 普通附件（文件名 -> 文件内容读取器）
+	// Unsubscribe 可选的退订方式，生成 List-Unsubscribe 和 List-Unsubscribe-Post 邮件头
+	Unsubscribe *Unsubscribe `json:"unsubscribe,omitempty"`
+
+	// Calendar 可选的会议邀请，作为 text/calendar 替代部分与正文一起发送
+	Calendar *CalendarEvent `json:"calendar,omitempty"`
+
+	// Files 附件列表（按添加顺序发送，可以显式指定 MIME 类型、Content-ID 等元数据）
+	Files []Attachment `json:"files"`
+
+	// Attachments 普通附件（文件名 -> 文件内容读取器），排在 Files 之后按文件名排序发送
 	Attachments map[string]io.Reader `json:"attachments"`
 
-	// InlineAttachments 内联附件（通常用于在HTML中嵌入图片）
+	// InlineAttachments 内联附件（通常用于在HTML中嵌入图片），Content-ID 为文件名
 	InlineAttachments map[string]io.Reader `json:"inlineAttachments"`
 }
 
@@ -55,6 +107,15 @@ type Mailer interface {
 	Send(message *Message) error
 }
 
+// ContextMailer 是一个可选接口，支持通过 context 控制发送过程
+// 例如在等待限流或熔断恢复时响应取消和超时
+type ContextMailer interface {
+	Mailer
+
+	// SendContext 发送一封邮件，ctx 被取消时尽快返回 ctx.Err()
+	SendContext(ctx context.Context, message *Message) error
+}
+
 // SendInterceptor 是一个可选接口，用于注册邮件发送钩子
 // 实现此接口可以在邮件发送前后执行自定义逻辑
 type SendInterceptor interface {
@@ -67,6 +128,61 @@ type SendEvent struct {
 	Event
 	// Message 正在发送的邮件消息
 	Message *Message
+
+	// MessageID 本次发送使用的 Message-ID（不含尖括号）
+	// 邮件未设置 Message-ID 时由传输自动生成，可以在钩子中记录以关联退信
+	MessageID string
+}
+
+// ErrMessageTooLarge 表示邮件大小超过了客户端或服务器的限制
+//
+// 所有传输在发送前检测到超限时返回的 *MessageSizeError 都满足 errors.Is(err, ErrMessageTooLarge)
+var ErrMessageTooLarge = errors.New("message too large")
+
+// MessageSizeError 描述了一次因邮件过大而失败的发送
+type MessageSizeError struct {
+	// Size 编码后的邮件大小（字节）
+	Size int64
+
+	// Limit 生效的大小限制（字节），未知时为 0
+	Limit int64
+
+	// Err 可选的底层错误，例如服务器返回的 552 响应
+	Err error
+}
+
+// Error 实现 error 接口
+func (e *MessageSizeError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("message size %d bytes rejected: %v", e.Size, e.Err)
+	}
+	return fmt.Sprintf("message size %d bytes exceeds the limit of %d bytes", e.Size, e.Limit)
+}
+
+// Is 使 errors.Is(err, ErrMessageTooLarge) 返回 true
+func (e *MessageSizeError) Is(target error) bool {
+	return target == ErrMessageTooLarge
+}
+
+// Unwrap 返回底层错误
+func (e *MessageSizeError) Unwrap() error {
+	return e.Err
+}
+
+// sendWithContext 使用指定的 context 通过 mailer 发送邮件
+//
+// 如果 mailer 实现了 ContextMailer 则调用 SendContext，
+// 否则在 ctx 未结束的情况下退化为普通的 Send 调用
+func sendWithContext(ctx context.Context, mailer Mailer, m *Message) error {
+	if cm, ok := mailer.(ContextMailer); ok {
+		return cm.SendContext(ctx, m)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return mailer.Send(m)
 }
 
 // addressesToStrings 将邮件地址列表转换为字符串列表
@@ -91,6 +207,28 @@ func addressesToStrings(addresses []mail.Address, withName bool) []string {
 	return result
 }
 
+// formatMessageID 将 Message-ID 规范化为带尖括号的 "<id>" 形式
+func formatMessageID(id string) string {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return ""
+	}
+
+	return "<" + strings.TrimSuffix(strings.TrimPrefix(id, "<"), ">") + ">"
+}
+
+// formatMessageIDs 将多个 Message-ID 规范化后以空格连接
+func formatMessageIDs(ids []string) string {
+	result := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if formatted := formatMessageID(id); formatted != "" {
+			result = append(result, formatted)
+		}
+	}
+
+	return strings.Join(result, " ")
+}
+
 // detectReaderMimeType 读取 Reader 的前几个字节来检测其 MIME 类型
 // 这对于正确设置附件的内容类型很重要
 //
@@ -0,0 +1,250 @@
+package gomailer
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"strings"
+)
+
+// ErrNotMDN 表示邮件不是阅读回执（multipart/report; report-type=disposition-notification）
+var ErrNotMDN = errors.New("message is not a disposition notification")
+
+// MDNDisposition 是阅读回执的处理结果（RFC 8098 第 3.2.6 节）
+type MDNDisposition string
+
+const (
+	// MDNDisplayed 邮件已经显示给收件人（不代表收件人已经阅读）
+	MDNDisplayed MDNDisposition = "displayed"
+
+	// MDNDeleted 邮件未显示就被删除
+	MDNDeleted MDNDisposition = "deleted"
+
+	// MDNDispatched 邮件未显示就被转交（如打印、转发）
+	MDNDispatched MDNDisposition = "dispatched"
+
+	// MDNProcessed 邮件已被自动处理，没有显示给收件人
+	MDNProcessed MDNDisposition = "processed"
+)
+
+// MDN 是解析后的阅读回执（Message Disposition Notification，RFC 8098）
+type MDN struct {
+	// OriginalMessageID 原邮件的 Message-ID（不含尖括号），用于关联发送记录
+	//
+	// 优先使用回执中的 Original-Message-ID 字段，没有时依次使用附带的原邮件头中的
+	// Message-ID 和回执邮件的 In-Reply-To
+	OriginalMessageID string
+
+	// FinalRecipient 生成回执的收件人地址
+	FinalRecipient string
+
+	// OriginalRecipient 原邮件信封中的收件人地址（可能为空）
+	OriginalRecipient string
+
+	// Disposition 处理结果
+	Disposition MDNDisposition
+
+	// ActionMode 处理方式："manual-action"（收件人操作）或 "automatic-action"
+	ActionMode string
+
+	// SendingMode 回执的发送方式："MDN-sent-manually" 或 "MDN-sent-automatically"
+	SendingMode string
+
+	// Modifiers 处理结果的修饰符（如 "error"），通常为空
+	Modifiers []string
+
+	// ReportingUA 生成回执的客户端
+	ReportingUA string
+
+	// Error 回执中的错误说明
+	Error string
+
+	// Text 回执中供人阅读的说明文字
+	Text string
+
+	// Fields 回执中的全部字段（包括扩展字段）
+	Fields textproto.MIMEHeader
+}
+
+// ParseMDN 解析阅读回执邮件
+//
+// 参数:
+//   - r: 原始邮件内容
+//
+// 返回:
+//   - *MDN: 解析后的回执
+//   - error: 邮件不是阅读回执时返回满足 errors.Is(err, ErrNotMDN) 的错误，格式错误时返回其它错误
+func ParseMDN(r io.Reader) (*MDN, error) {
+	raw, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, fmt.Errorf("parse mdn: %w", err)
+	}
+
+	p := &mdnParser{}
+	if err := p.walk(textproto.MIMEHeader(raw.Header), raw.Body, 0); err != nil {
+		return nil, fmt.Errorf("parse mdn: %w", err)
+	}
+
+	if p.fields == nil {
+		return nil, ErrNotMDN
+	}
+
+	fields := p.fields
+	mdn := &MDN{
+		FinalRecipient:    reportAddress(fields.Get("Final-Recipient")),
+		OriginalRecipient: reportAddress(fields.Get("Original-Recipient")),
+		ReportingUA:       strings.TrimSpace(fields.Get("Reporting-UA")),
+		Error:             strings.TrimSpace(fields.Get("Error")),
+		Text:              strings.TrimSpace(p.text),
+		Fields:            fields,
+	}
+
+	if mdn.FinalRecipient == "" {
+		return nil, errors.New("parse mdn: missing Final-Recipient")
+	}
+
+	mdn.parseDisposition(fields.Get("Disposition"))
+	if mdn.Disposition == "" {
+		return nil, errors.New("parse mdn: missing or invalid Disposition")
+	}
+
+	// 关联原邮件
+	candidates := []string{fields.Get("Original-Message-ID"), p.originalMessageID, raw.Header.Get("In-Reply-To")}
+	for _, value := range candidates {
+		if ids := parseMessageIDs(value); len(ids) > 0 {
+			mdn.OriginalMessageID = ids[0]
+			break
+		}
+	}
+
+	return mdn, nil
+}
+
+// parseDisposition 解析 Disposition 字段，如
+// "manual-action/MDN-sent-manually; displayed/error"
+func (mdn *MDN) parseDisposition(value string) {
+	modes, result, ok := strings.Cut(value, ";")
+	if !ok {
+		return
+	}
+
+	action, sending, _ := strings.Cut(strings.TrimSpace(modes), "/")
+	mdn.ActionMode = strings.TrimSpace(action)
+	mdn.SendingMode = strings.TrimSpace(sending)
+
+	// 去掉可能存在的注释
+	if i := strings.IndexByte(result, '('); i >= 0 {
+		result = result[:i]
+	}
+
+	disposition, modifiers, _ := strings.Cut(strings.TrimSpace(result), "/")
+	mdn.Disposition = MDNDisposition(strings.ToLower(strings.TrimSpace(disposition)))
+
+	for _, modifier := range strings.Split(modifiers, ",") {
+		if modifier = strings.TrimSpace(modifier); modifier != "" {
+			mdn.Modifiers = append(mdn.Modifiers, modifier)
+		}
+	}
+}
+
+// reportAddress 从 "rfc822; user@example.com" 形式的字段中取出地址
+func reportAddress(value string) string {
+	if _, address, ok := strings.Cut(value, ";"); ok {
+		value = address
+	}
+	return strings.TrimSpace(value)
+}
+
+// mdnParser 在 MIME 结构中查找阅读回执的各个部分
+type mdnParser struct {
+	// fields message/disposition-notification 部分的字段
+	fields textproto.MIMEHeader
+
+	// text 第一个 text/plain 部分
+	text string
+
+	// originalMessageID 附带的原邮件（或原邮件头）中的 Message-ID
+	originalMessageID string
+}
+
+// walk 处理一个 MIME 实体
+func (p *mdnParser) walk(header textproto.MIMEHeader, body io.Reader, depth int) error {
+	if depth > maxMultipartDepth {
+		return errors.New("multipart nesting too deep")
+	}
+
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		boundary := params["boundary"]
+		if boundary == "" {
+			return fmt.Errorf("%s without boundary", mediaType)
+		}
+
+		reader := multipart.NewReader(body, boundary)
+		for {
+			part, err := reader.NextRawPart()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			err = p.walk(part.Header, part, depth+1)
+			part.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := io.ReadAll(decodeTransferEncoding(header.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return err
+	}
+
+	switch mediaType {
+	case "message/disposition-notification":
+		if p.fields == nil {
+			fields, err := readReportFields(data)
+			if err != nil {
+				return err
+			}
+			p.fields = fields
+		}
+	case "text/rfc822-headers", "message/rfc822", "message/rfc822-headers":
+		if p.originalMessageID == "" {
+			if original, err := readReportFields(data); err == nil {
+				p.originalMessageID = original.Get("Message-ID")
+			}
+		}
+	case "text/plain":
+		if p.text == "" {
+			p.text = decodeText(params["charset"], data)
+		}
+	}
+
+	return nil
+}
+
+// readReportFields 读取回执字段或原邮件头，内容在第一个空行处结束
+func readReportFields(data []byte) (textproto.MIMEHeader, error) {
+	data = append(bytes.TrimLeft(data, "\r\n"), "\r\n\r\n"...)
+
+	fields, err := textproto.NewReader(bufio.NewReader(bytes.NewReader(data))).ReadMIMEHeader()
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	return fields, nil
+}
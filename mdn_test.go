@@ -0,0 +1,159 @@
+package gomailer
+
+import (
+	"errors"
+	"net/mail"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// thunderbirdMDN 是 Thunderbird 格式的阅读回执，另外加了一个扩展字段
+const thunderbirdMDN = "Return-Path: <bob@example.org>\r\n" +
+	"From: Bob <bob@example.org>\r\n" +
+	"To: Legal <legal@example.com>\r\n" +
+	"Subject: =?UTF-8?B?5bey6ZiF?=: Contract notice\r\n" +
+	"Message-ID: <mdn.1@example.org>\r\n" +
+	"In-Reply-To: <notice.7@example.com>\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/report; report-type=disposition-notification;\r\n" +
+	" boundary=\"------------mdn-boundary\"\r\n" +
+	"\r\n" +
+	"This is a multi-part message in MIME format.\r\n" +
+	"--------------mdn-boundary\r\n" +
+	"Content-Type: text/plain; charset=UTF-8\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"5oKo5Y+R6YCB55qE6YKu5Lu25bey6KKr6ZiF6K+744CC\r\n" +
+	"\r\n" +
+	"--------------mdn-boundary\r\n" +
+	"Content-Type: message/disposition-notification; name=\"MDNPart2.txt\"\r\n" +
+	"Content-Disposition: inline\r\n" +
+	"Content-Transfer-Encoding: 7bit\r\n" +
+	"\r\n" +
+	"Reporting-UA: mail.example.org; Thunderbird 128.0\r\n" +
+	"Original-Recipient: rfc822;bob@example.org\r\n" +
+	"Final-Recipient: rfc822;Bob@Example.org\r\n" +
+	"Original-Message-ID: <notice.7@example.com>\r\n" +
+	"Disposition: manual-action/MDN-sent-manually; displayed\r\n" +
+	"X-Custom-Field: kept\r\n" +
+	"\r\n" +
+	"--------------mdn-boundary\r\n" +
+	"Content-Type: text/rfc822-headers; name=\"MDNPart3.txt\"\r\n" +
+	"Content-Disposition: inline\r\n" +
+	"Content-Transfer-Encoding: 7bit\r\n" +
+	"\r\n" +
+	"From: Legal <legal@example.com>\r\n" +
+	"To: Bob <bob@example.org>\r\n" +
+	"Message-ID: <notice.7@example.com>\r\n" +
+	"Subject: Contract notice\r\n" +
+	"\r\n" +
+	"--------------mdn-boundary--\r\n"
+
+func TestParseMDN(t *testing.T) {
+	mdn, err := ParseMDN(strings.NewReader(thunderbirdMDN))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &MDN{
+		OriginalMessageID: "notice.7@example.com",
+		FinalRecipient:    "Bob@Example.org",
+		OriginalRecipient: "bob@example.org",
+		Disposition:       MDNDisplayed,
+		ActionMode:        "manual-action",
+		SendingMode:       "MDN-sent-manually",
+		ReportingUA:       "mail.example.org; Thunderbird 128.0",
+		Text:              "您发送的邮件已被阅读。",
+	}
+	fields := mdn.Fields
+	mdn.Fields = nil
+	if !reflect.DeepEqual(mdn, want) {
+		t.Errorf("mdn = %+v\nwant  %+v", mdn, want)
+	}
+	if fields.Get("X-Custom-Field") != "kept" {
+		t.Errorf("Fields = %v, want extension fields kept", fields)
+	}
+}
+
+func TestParseMDNOriginalMessageIDFallback(t *testing.T) {
+	// 没有 Original-Message-ID 时使用附带的原邮件头
+	withoutField := strings.Replace(thunderbirdMDN, "Original-Message-ID: <notice.7@example.com>\r\n", "", 1)
+	headersOnly := strings.Replace(withoutField, "In-Reply-To: <notice.7@example.com>\r\n", "In-Reply-To: <other@example.com>\r\n", 1)
+
+	mdn, err := ParseMDN(strings.NewReader(headersOnly))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mdn.OriginalMessageID != "notice.7@example.com" {
+		t.Errorf("OriginalMessageID = %q, want the attached Message-ID", mdn.OriginalMessageID)
+	}
+
+	// 都没有时使用回执的 In-Reply-To
+	noHeaders := strings.Replace(withoutField, "Message-ID: <notice.7@example.com>\r\nSubject: Contract notice\r\n", "Subject: Contract notice\r\n", 1)
+	mdn, err = ParseMDN(strings.NewReader(noHeaders))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mdn.OriginalMessageID != "notice.7@example.com" {
+		t.Errorf("OriginalMessageID = %q, want In-Reply-To", mdn.OriginalMessageID)
+	}
+}
+
+func TestParseMDNDispositionModifiers(t *testing.T) {
+	raw := strings.Replace(thunderbirdMDN,
+		"Disposition: manual-action/MDN-sent-manually; displayed\r\n",
+		"Disposition: automatic-action/MDN-sent-automatically; Deleted/error (mailbox full)\r\n", 1)
+
+	mdn, err := ParseMDN(strings.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mdn.Disposition != MDNDeleted || mdn.ActionMode != "automatic-action" || !reflect.DeepEqual(mdn.Modifiers, []string{"error"}) {
+		t.Errorf("mdn = %+v", mdn)
+	}
+}
+
+func TestParseMDNErrors(t *testing.T) {
+	dsn := strings.NewReplacer(
+		"report-type=disposition-notification", "report-type=delivery-status",
+		"message/disposition-notification", "message/delivery-status",
+	).Replace(thunderbirdMDN)
+
+	tests := []struct {
+		name    string
+		raw     string
+		wantMDN bool
+	}{
+		{"plain message", "From: a@example.com\r\nSubject: hi\r\n\r\nhello\r\n", false},
+		{"delivery status notification", dsn, false},
+		{"missing final recipient", strings.Replace(thunderbirdMDN, "Final-Recipient: rfc822;Bob@Example.org\r\n", "", 1), true},
+		{"missing disposition", strings.Replace(thunderbirdMDN, "Disposition: manual-action/MDN-sent-manually; displayed\r\n", "", 1), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseMDN(strings.NewReader(tt.raw))
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if errors.Is(err, ErrNotMDN) == tt.wantMDN {
+				t.Errorf("err = %v, ErrNotMDN = %v", err, !tt.wantMDN)
+			}
+		})
+	}
+}
+
+func TestReadReceiptHeader(t *testing.T) {
+	m := messageTo("to@example.com")
+	m.ReadReceiptTo = []mail.Address{{Name: "Legal", Address: "legal@example.com"}}
+
+	h := readHeader(t, string(renderBytes(t, m)))
+	if got := h.Get("Disposition-Notification-To"); got != `"Legal" <legal@example.com>` {
+		t.Errorf("Disposition-Notification-To = %q", got)
+	}
+
+	if parsed := reparse(t, m); !reflect.DeepEqual(parsed.ReadReceiptTo, m.ReadReceiptTo) {
+		t.Errorf("parsed ReadReceiptTo = %v", parsed.ReadReceiptTo)
+	}
+}
@@ -0,0 +1,100 @@
+package gomailer
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// messageIDEncoding 用于 Message-ID 随机部分的编码（小写 base32，不含填充）
+var messageIDEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// GenerateMessageID 生成一个全局唯一的 Message-ID（不含尖括号）
+//
+// 格式为 "<时间戳>.<随机串>@<domain>"，随机部分来自 crypto/rand（128 位），
+// 可以在发送前设置到 Message.MessageID，用于关联退信等后续事件
+//
+// 参数:
+//   - domain: Message-ID 的域名部分，通常为发件人域名；为空时使用本机主机名
+//
+// 返回:
+//   - string: 生成的 Message-ID
+//   - error: 域名无效或无法读取随机数时返回错误
+func GenerateMessageID(domain string) (string, error) {
+	if domain == "" {
+		domain = hostDomain()
+	}
+
+	if !validMessageID("x@" + domain) {
+		return "", errors.New("invalid message id domain " + strconv.Quote(domain))
+	}
+
+	random, err := randomIDPart(16)
+	if err != nil {
+		return "", err
+	}
+
+	return strconv.FormatInt(time.Now().UnixMilli(), 36) + "." + random + "@" + domain, nil
+}
+
+// randomIDPart 返回 size 字节 crypto/rand 随机数的编码，用于 Message-ID 和 Content-ID
+func randomIDPart(size int) (string, error) {
+	random := make([]byte, size)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return messageIDEncoding.EncodeToString(random), nil
+}
+
+// messageIDDomain 返回生成 Message-ID 时使用的域名：
+// 传输配置的域名 > 发件人地址的域名 > 本机主机名
+func messageIDDomain(configured string, m *Message) string {
+	if configured != "" {
+		return configured
+	}
+
+	if at := strings.LastIndex(m.From.Address, "@"); at >= 0 {
+		if domain := m.From.Address[at+1:]; domain != "" && validMessageID("x@"+domain) {
+			return domain
+		}
+	}
+
+	return hostDomain()
+}
+
+// hostDomain 返回本机主机名，无法获取或不含点号时返回 "localhost.localdomain"
+func hostDomain() string {
+	if name, err := os.Hostname(); err == nil && strings.Contains(name, ".") && validMessageID("x@"+name) {
+		return name
+	}
+	return "localhost.localdomain"
+}
+
+// messageIDOf 返回邮件已有的 Message-ID（MessageID 字段或 Headers 中的 Message-ID），
+// 不含尖括号，没有时返回空字符串
+func messageIDOf(m *Message) string {
+	id := m.MessageID
+	if id == "" {
+		for k, v := range m.Headers {
+			if strings.EqualFold(k, "Message-ID") {
+				id = v
+				break
+			}
+		}
+	}
+
+	return strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(id), "<"), ">")
+}
+
+// ensureMessageID 返回邮件的 Message-ID，没有时使用 domain 生成一个新的
+func ensureMessageID(m *Message, domain string) (string, error) {
+	if id := messageIDOf(m); id != "" {
+		return id, nil
+	}
+
+	return GenerateMessageID(messageIDDomain(domain, m))
+}
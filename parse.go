@@ -0,0 +1,393 @@
+package gomailer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// parsedHeaders 是 ParseMessage 映射到 Message 字段的邮件头，不会再放入 Message.Headers
+var parsedHeaders = []string{
+	"From", "To", "Cc", "Bcc", "Reply-To", "Sender", "Disposition-Notification-To",
+	"Subject", "Date", "Message-ID", "In-Reply-To", "References",
+	"List-Unsubscribe", "List-Unsubscribe-Post",
+	"X-Priority", "Importance", "Priority",
+	"MIME-Version", "Content-Type", "Content-Transfer-Encoding", "Content-Disposition",
+}
+
+// traceHeaders 是传输过程中添加的邮件头，重新发送时不再有意义（DKIM 等签名在重新渲染后也会失效）
+var traceHeaders = []string{
+	"Received", "Return-Path", "Delivered-To", "X-Original-To",
+	"DKIM-Signature", "Authentication-Results",
+	"ARC-Seal", "ARC-Message-Signature", "ARC-Authentication-Results",
+}
+
+// ParseMessage 将 RFC 5322 格式的原始邮件（如 .eml 文件）解析为 Message
+//
+// 解析内容包括：
+//   - 地址、主题等邮件头（RFC 2047 编码字会被解码）
+//   - Message-ID、In-Reply-To、References、List-Unsubscribe 和优先级
+//   - multipart 结构：第一个 text/plain 和 text/html 实体作为正文，带 method 参数的 text/calendar 实体还原为 Calendar，
+//     其它实体按出现顺序作为附件放入 Files，带 Content-ID 的内联实体保持内联
+//   - base64 和 quoted-printable 传输编码
+//
+// 其它邮件头放入 Headers（同名邮件头只保留第一个），Received、DKIM-Signature 等传输和签名相关的邮件头会被丢弃。
+// 附件内容会被读入内存
+//
+// 参数:
+//   - r: 原始邮件内容
+//
+// 返回:
+//   - *Message: 解析后的邮件，可以直接通过 SMTPClient 等发送
+//   - error: 邮件格式错误时返回错误
+func ParseMessage(r io.Reader) (*Message, error) {
+	raw, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, fmt.Errorf("parse message: %w", err)
+	}
+
+	m := &Message{}
+	header := raw.Header
+	decoder := newWordDecoder()
+
+	// 地址
+	if from, err := parseAddressHeader(decoder, header, "From"); err != nil {
+		return nil, err
+	} else if len(from) > 0 {
+		m.From = from[0]
+	}
+
+	if sender, err := parseAddressHeader(decoder, header, "Sender"); err != nil {
+		return nil, err
+	} else if len(sender) > 0 {
+		m.Sender = sender[0]
+	}
+
+	for _, f := range []struct {
+		name string
+		dst  *[]mail.Address
+	}{
+		{"To", &m.To},
+		{"Cc", &m.Cc},
+		{"Bcc", &m.Bcc},
+		{"Reply-To", &m.ReplyTo},
+		{"Disposition-Notification-To", &m.ReadReceiptTo},
+	} {
+		if *f.dst, err = parseAddressHeader(decoder, header, f.name); err != nil {
+			return nil, err
+		}
+	}
+
+	// 主题和会话头
+	m.Subject = decodeHeaderValue(decoder, header.Get("Subject"))
+
+	if ids := parseMessageIDs(header.Get("Message-ID")); len(ids) > 0 {
+		m.MessageID = ids[0]
+	}
+	if ids := parseMessageIDs(header.Get("In-Reply-To")); len(ids) > 0 {
+		m.InReplyTo = ids[0]
+	}
+	m.References = parseMessageIDs(header.Get("References"))
+
+	m.Unsubscribe = parseListUnsubscribe(header)
+	m.Priority = parsePriority(header)
+
+	// 其它邮件头
+	for name, values := range header {
+		canonical := textproto.CanonicalMIMEHeaderKey(name)
+		if len(values) == 0 || containsFold(parsedHeaders, canonical) || containsFold(traceHeaders, canonical) {
+			continue
+		}
+		if m.Headers == nil {
+			m.Headers = map[string]string{}
+		}
+		m.Headers[canonical] = decodeHeaderValue(decoder, values[0])
+	}
+
+	// 正文和附件
+	p := &messageParser{msg: m, decoder: decoder}
+	if err := p.walk(textproto.MIMEHeader(header), raw.Body, 0); err != nil {
+		return nil, fmt.Errorf("parse message: %w", err)
+	}
+
+	return m, nil
+}
+
+// parseAddressHeader 解析地址列表邮件头，邮件头不存在时返回 nil
+// 姓名中的编码字使用 decoder 解码，以支持 UTF-8 以外的字符集
+func parseAddressHeader(decoder *mime.WordDecoder, header mail.Header, name string) ([]mail.Address, error) {
+	if strings.TrimSpace(header.Get(name)) == "" {
+		return nil, nil
+	}
+
+	parser := &mail.AddressParser{WordDecoder: decoder}
+	list, err := parser.ParseList(header.Get(name))
+	if err != nil {
+		return nil, fmt.Errorf("parse message: header %q: %w", name, err)
+	}
+
+	result := make([]mail.Address, len(list))
+	for i, addr := range list {
+		result[i] = *addr
+	}
+
+	return result, nil
+}
+
+// decodeHeaderValue 解码 RFC 2047 编码字，无法解码时返回原值
+func decodeHeaderValue(decoder *mime.WordDecoder, value string) string {
+	decoded, err := decoder.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
+// parseMessageIDs 从 Message-ID/In-Reply-To/References 的值中提取所有 "<id>"（返回值不含尖括号）
+func parseMessageIDs(value string) []string {
+	var ids []string
+
+	for {
+		start := strings.IndexByte(value, '<')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(value[start:], '>')
+		if end < 0 {
+			break
+		}
+
+		if id := strings.TrimSpace(value[start+1 : start+end]); id != "" {
+			ids = append(ids, id)
+		}
+		value = value[start+end+1:]
+	}
+
+	// 不规范的邮件可能省略尖括号
+	if len(ids) == 0 {
+		if id := strings.TrimSpace(value); id != "" && !strings.ContainsAny(id, " \t") {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+// parseListUnsubscribe 解析 List-Unsubscribe 和 List-Unsubscribe-Post 邮件头
+func parseListUnsubscribe(header mail.Header) *Unsubscribe {
+	var u Unsubscribe
+	for _, uri := range parseMessageIDs(header.Get("List-Unsubscribe")) {
+		switch lower := strings.ToLower(uri); {
+		case strings.HasPrefix(lower, "mailto:") && u.Mailto == "":
+			u.Mailto = uri
+		case (strings.HasPrefix(lower, "https:") || strings.HasPrefix(lower, "http:")) && u.URL == "":
+			u.URL = uri
+		}
+	}
+
+	if u.URL == "" && u.Mailto == "" {
+		return nil
+	}
+
+	u.OneClick = u.URL != "" && strings.EqualFold(strings.TrimSpace(header.Get("List-Unsubscribe-Post")), "List-Unsubscribe=One-Click")
+	return &u
+}
+
+// containsFold 检查列表中是否存在与 s 忽略大小写相等的元素
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// maxMultipartDepth 限制 multipart 的嵌套层数，防止恶意构造的邮件耗尽栈空间
+const maxMultipartDepth = 32
+
+// messageParser 遍历 MIME 结构并填充 Message
+type messageParser struct {
+	msg     *Message
+	decoder *mime.WordDecoder
+}
+
+// walk 处理一个 MIME 实体
+func (p *messageParser) walk(header textproto.MIMEHeader, body io.Reader, depth int) error {
+	if depth > maxMultipartDepth {
+		return errors.New("multipart nesting too deep")
+	}
+
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "text/plain"
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		// 无法解析的类型按 RFC 2045 视为 application/octet-stream
+		mediaType, params = "application/octet-stream", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		boundary := params["boundary"]
+		if boundary == "" {
+			return fmt.Errorf("%s without boundary", mediaType)
+		}
+
+		reader := multipart.NewReader(body, boundary)
+		for {
+			part, err := reader.NextRawPart()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			err = p.walk(part.Header, part, depth+1)
+			part.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := io.ReadAll(decodeTransferEncoding(header.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return err
+	}
+
+	disposition, dispositionParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	filename := p.filename(dispositionParams["filename"], params["name"])
+
+	// 带 method 参数的 text/calendar 替代部分是会议邀请（RFC 6047），还原为 Calendar，无法解析时按附件处理
+	if mediaType == "text/calendar" && params["method"] != "" && disposition != DispositionAttachment &&
+		filename == "" && p.msg.Calendar == nil {
+		if e, err := parseICS(decodeText(params["charset"], data), params["method"]); err == nil {
+			// 渲染时 Summary 为空会使用邮件主题
+			if e.Summary == p.msg.Subject {
+				e.Summary = ""
+			}
+			p.msg.Calendar = e
+			return nil
+		}
+	}
+
+	// 第一个没有文件名、不是附件的文本实体作为正文
+	if disposition != DispositionAttachment && filename == "" {
+		switch {
+		case mediaType == "text/plain" && p.msg.Text == "":
+			p.msg.Text = decodeText(params["charset"], data)
+			return nil
+		case mediaType == "text/html" && p.msg.HTML == "":
+			p.msg.HTML = decodeText(params["charset"], data)
+			return nil
+		}
+	}
+
+	a := Attachment{
+		Filename:    filename,
+		ContentType: contentType,
+		Disposition: DispositionAttachment,
+		Reader:      bytes.NewReader(data),
+	}
+
+	if cid := parseMessageIDs(header.Get("Content-ID")); len(cid) > 0 && disposition != DispositionAttachment {
+		a.Disposition = DispositionInline
+		a.ContentID = cid[0]
+	} else if disposition == DispositionInline && filename != "" {
+		a.Disposition = DispositionInline
+	}
+
+	if a.Filename == "" {
+		a.Filename = "attachment-" + strconv.Itoa(len(p.msg.Files)+1) + extensionForType(mediaType)
+	}
+
+	// 渲染时会重新添加 name 参数
+	if _, ok := params["name"]; ok {
+		delete(params, "name")
+		a.ContentType = mime.FormatMediaType(mediaType, params)
+	}
+
+	p.msg.Files = append(p.msg.Files, a)
+	return nil
+}
+
+// filename 返回实体的文件名：优先使用 Content-Disposition 的 filename，其次是 Content-Type 的 name
+// （mime.ParseMediaType 已处理 RFC 2231 编码，这里再解码不规范但常见的 RFC 2047 编码字）
+func (p *messageParser) filename(names ...string) string {
+	for _, name := range names {
+		if name != "" {
+			return decodeHeaderValue(p.decoder, name)
+		}
+	}
+	return ""
+}
+
+// decodeTransferEncoding 根据 Content-Transfer-Encoding 返回解码后的 Reader
+func decodeTransferEncoding(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &base64Cleaner{r: r})
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	default:
+		// 7bit、8bit、binary 不需要解码
+		return r
+	}
+}
+
+// base64Cleaner 去掉 base64 内容中的换行和空白
+type base64Cleaner struct {
+	r io.Reader
+}
+
+// Read 实现 io.Reader 接口
+func (c *base64Cleaner) Read(p []byte) (int, error) {
+	for {
+		n, err := c.r.Read(p)
+
+		clean := 0
+		for _, b := range p[:n] {
+			if b != '\r' && b != '\n' && b != ' ' && b != '\t' {
+				p[clean] = b
+				clean++
+			}
+		}
+
+		// 整块都是空白时继续读取，避免返回 0, nil
+		if clean > 0 || err != nil {
+			return clean, err
+		}
+	}
+}
+
+// decodeText 将文本内容按 charset 转换为 UTF-8 字符串，未知的字符集保持原始字节
+func decodeText(charset string, data []byte) string {
+	text, err := decodeCharset(charset, data)
+	if err != nil {
+		return string(data)
+	}
+	return text
+}
+
+// extensionForType 返回 MIME 类型对应的扩展名（包含点号），未知时返回空字符串
+func extensionForType(mediaType string) string {
+	for ext, t := range extensionTypes {
+		if t == mediaType {
+			return ext
+		}
+	}
+
+	return imageExtension(mediaType)
+}
@@ -0,0 +1,204 @@
+package gomailer
+
+import (
+	"bytes"
+	"net/mail"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// renderBytes 渲染完整邮件
+func renderBytes(t testing.TB, m *Message) []byte {
+	t.Helper()
+
+	id, err := ensureMessageID(m, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, err := buildHeader(m, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := renderMessage(header, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := r.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// reparse 渲染邮件后重新解析
+func reparse(t testing.TB, m *Message) *Message {
+	t.Helper()
+
+	parsed, err := ParseMessage(bytes.NewReader(renderBytes(t, m)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func TestParseMessageCalendarRoundTrip(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skip("time zone database is not available")
+	}
+
+	tests := []struct {
+		name  string
+		start time.Time
+	}{
+		{"utc", time.Date(2026, 11, 2, 2, 0, 0, 0, time.UTC)},
+		{"tzid", time.Date(2026, 11, 2, 10, 0, 0, 0, shanghai)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := &CalendarEvent{
+				Method:    CalendarRequest,
+				UID:       "interview-42@example.com",
+				Sequence:  2,
+				Organizer: mail.Address{Name: "招聘团队", Address: "hr@example.com"},
+				Attendees: []CalendarAttendee{
+					{Address: mail.Address{Name: "Zhang, San", Address: "zhangsan@example.com"}},
+					{Address: mail.Address{Address: "observer@example.com"}, Optional: true},
+				},
+				Description: "议程：\n1. 自我介绍; 2. 技术面试\\白板",
+				Location:    "3 号会议室, " + strings.Repeat("很长的地址", 10),
+				Start:       tt.start,
+				End:         tt.start.Add(time.Hour),
+			}
+
+			m := &Message{
+				From:     mail.Address{Address: "hr@example.com"},
+				To:       []mail.Address{{Address: "zhangsan@example.com"}},
+				Subject:  "面试邀请",
+				Text:     "请查收面试邀请",
+				HTML:     "<p>请查收面试邀请</p>",
+				Calendar: event,
+				Files:    []Attachment{{Filename: "resume.txt", Reader: strings.NewReader("resume")}},
+			}
+
+			first := reparse(t, m)
+			if first.Calendar == nil {
+				t.Fatalf("Calendar = nil, Files = %d", len(first.Files))
+			}
+			if len(first.Files) != 1 || first.Files[0].Filename != "resume.txt" {
+				t.Fatalf("calendar must not be parsed as an attachment: %+v", first.Files)
+			}
+			if first.Text != m.Text || first.HTML != m.HTML {
+				t.Errorf("body = %q / %q", first.Text, first.HTML)
+			}
+			assertCalendarEqual(t, first.Calendar, event)
+
+			// 再次渲染得到相同的日程
+			now := time.Now()
+			if got, want := first.Calendar.ics(first.Subject, now), event.ics(m.Subject, now); got != want {
+				t.Errorf("re-rendered calendar differs:\n%s\nwant:\n%s", got, want)
+			}
+
+			second := reparse(t, first)
+			if second.Calendar == nil {
+				t.Fatal("Calendar = nil after second round trip")
+			}
+			assertCalendarEqual(t, second.Calendar, event)
+		})
+	}
+}
+
+func TestParseMessageCalendarUnknownTimeZone(t *testing.T) {
+	mars := time.FixedZone("Mars/Olympus", 3600)
+	start := time.Date(2026, 11, 2, 10, 0, 0, 0, mars)
+
+	m := &Message{
+		From:    mail.Address{Address: "hr@example.com"},
+		To:      []mail.Address{{Address: "zhangsan@example.com"}},
+		Subject: "meeting",
+		Text:    "see invite",
+		Calendar: &CalendarEvent{
+			UID:       "mars@example.com",
+			Organizer: mail.Address{Address: "hr@example.com"},
+			Attendees: []CalendarAttendee{{Address: mail.Address{Address: "zhangsan@example.com"}}},
+			Start:     start,
+			End:       start.Add(time.Hour),
+		},
+	}
+
+	parsed := reparse(t, m)
+	if parsed.Calendar != nil {
+		t.Fatalf("Calendar = %+v, want nil", parsed.Calendar)
+	}
+	if len(parsed.Files) != 1 || !strings.HasPrefix(parsed.Files[0].ContentType, "text/calendar") {
+		t.Fatalf("unparsable calendar must be kept as an attachment: %+v", parsed.Files)
+	}
+}
+
+func TestParseICS(t *testing.T) {
+	ics := "BEGIN:VCALENDAR\r\n" +
+		"METHOD:CANCEL\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:abc\r\n" +
+		"SEQUENCE:3\r\n" +
+		"DTSTART:20261102T020000Z\r\n" +
+		"DTEND:20261102T030000Z\r\n" +
+		"SUMMARY:Weekly\\, sync\r\n" +
+		"ORGANIZER;CN=\"Boss: Team;A\":MAILTO:boss@example.com\r\n" +
+		"ATTENDEE;ROLE=OPT-PARTICIPANT;CN=Dev:mailto:dev@exam\r\n" +
+		" ple.com\r\n" +
+		"BEGIN:VALARM\r\n" +
+		"DESCRIPTION:reminder\r\n" +
+		"END:VALARM\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:second\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	got, err := parseICS(ics, "request")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2026, 11, 2, 2, 0, 0, 0, time.UTC)
+	want := &CalendarEvent{
+		Method:    CalendarCancel,
+		UID:       "abc",
+		Sequence:  3,
+		Organizer: mail.Address{Name: "Boss: Team;A", Address: "boss@example.com"},
+		Attendees: []CalendarAttendee{{Address: mail.Address{Name: "Dev", Address: "dev@example.com"}, Optional: true}},
+		Summary:   "Weekly, sync",
+		Start:     start,
+		End:       start.Add(time.Hour),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseICS = %+v, want %+v", got, want)
+	}
+
+	if _, err := parseICS("BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n", "REQUEST"); err == nil {
+		t.Error("expected error for calendar without VEVENT")
+	}
+}
+
+// assertCalendarEqual 比较日程，时间按时刻和时区名称比较
+func assertCalendarEqual(t *testing.T, got, want *CalendarEvent) {
+	t.Helper()
+
+	if !got.Start.Equal(want.Start) || !got.End.Equal(want.End) {
+		t.Errorf("time = %v - %v, want %v - %v", got.Start, got.End, want.Start, want.End)
+	}
+	if got.Start.Location().String() != want.Start.Location().String() {
+		t.Errorf("location = %s, want %s", got.Start.Location(), want.Start.Location())
+	}
+
+	g, w := *got, *want
+	g.Start, g.End, w.Start, w.End = time.Time{}, time.Time{}, time.Time{}, time.Time{}
+	if !reflect.DeepEqual(g, w) {
+		t.Errorf("calendar = %+v, want %+v", g, w)
+	}
+}
@@ -0,0 +1,322 @@
+package gomailer
+
+import (
+	"bytes"
+	"crypto"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// 确保 OpenPGP 实现了 Transformer 接口，PGPKeyring 实现了 PGPKeyLookup 接口
+var (
+	_ Transformer  = (*OpenPGP)(nil)
+	_ PGPKeyLookup = PGPKeyring(nil)
+)
+
+// PGPKeyLookup 查找收件人的 OpenPGP 公钥
+type PGPKeyLookup interface {
+	// LookupPGPKey 返回收件人地址对应的公钥
+	//
+	// 没有找到公钥时返回 nil, nil（按 OpenPGP.MissingKey 处理），
+	// 返回错误时发送失败
+	LookupPGPKey(address string) (*openpgp.Entity, error)
+}
+
+// PGPKeyLookupFunc 将普通函数适配为 PGPKeyLookup
+type PGPKeyLookupFunc func(address string) (*openpgp.Entity, error)
+
+// LookupPGPKey 实现 PGPKeyLookup 接口
+func (f PGPKeyLookupFunc) LookupPGPKey(address string) (*openpgp.Entity, error) {
+	return f(address)
+}
+
+// PGPKeyring 是一组公钥，按用户 ID 中的邮箱地址（不区分大小写）查找
+//
+// 示例:
+//
+//	keys, err := openpgp.ReadArmoredKeyRing(f)
+//	pgp := &OpenPGP{Encrypt: true, Keys: PGPKeyring(keys)}
+type PGPKeyring openpgp.EntityList
+
+// LookupPGPKey 实现 PGPKeyLookup 接口
+func (k PGPKeyring) LookupPGPKey(address string) (*openpgp.Entity, error) {
+	for _, entity := range k {
+		for _, identity := range entity.Identities {
+			if identity.UserId != nil && strings.EqualFold(identity.UserId.Email, address) {
+				return entity, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// PGPMissingKeyPolicy 定义加密时收件人没有可用公钥的处理方式
+type PGPMissingKeyPolicy int
+
+const (
+	// PGPMissingKeyFail 返回错误，邮件不会被发送（默认）
+	PGPMissingKeyFail PGPMissingKeyPolicy = iota
+
+	// PGPMissingKeySendPlain 整封邮件不加密发送给所有收件人（设置了 Signer 时仍然签名）
+	PGPMissingKeySendPlain
+
+	// PGPMissingKeySkipRecipient 不向缺少公钥的收件人发送，其余收件人正常收到加密邮件
+	PGPMissingKeySkipRecipient
+)
+
+// PGPMissingKeyError 表示加密时有收件人没有可用的公钥
+type PGPMissingKeyError struct {
+	// Recipients 缺少公钥的收件人地址
+	Recipients []string
+}
+
+// Error 实现 error 接口
+func (e *PGPMissingKeyError) Error() string {
+	return fmt.Sprintf("openpgp: no public key for %s", strings.Join(e.Recipients, ", "))
+}
+
+// OpenPGP 使用 OpenPGP/MIME（RFC 3156）对邮件签名和加密，作为 Transformer 配置在传输上
+//
+//   - 只签名：生成 multipart/signed，签名为分离的 ASCII armor 签名（application/pgp-signature）
+//   - 加密：生成 multipart/encrypted，设置了 Signer 时签名包含在密文中（RFC 3156 6.2 节的组合方式）
+//
+// 加密的邮件不能有 Bcc 收件人：密文中每个收件人的密钥 ID 对所有收件人可见，
+// 需要加密发送给 Bcc 收件人时，为每个人单独发送一封邮件
+//
+// 示例:
+//
+//	keys, _ := openpgp.ReadArmoredKeyRing(publicKeys)
+//	client := &SMTPClient{
+//		// ...
+//		Transformers: []Transformer{&OpenPGP{
+//			Signer:     signer,
+//			Encrypt:    true,
+//			Keys:       PGPKeyring(keys),
+//			MissingKey: PGPMissingKeySkipRecipient,
+//		}},
+//	}
+type OpenPGP struct {
+	// Signer 签名使用的私钥，设置后对邮件签名（私钥必须已经解密）
+	Signer *openpgp.Entity
+
+	// Encrypt 是否加密邮件，加密时通过 Keys 查找每个信封收件人的公钥，
+	// 邮件有 Bcc 收件人时返回 ValidationErrors
+	Encrypt bool
+
+	// Keys 收件人公钥查找，加密时必须设置
+	Keys PGPKeyLookup
+
+	// MissingKey 收件人没有可用公钥时的处理方式
+	// 如果未明确设置，默认为 PGPMissingKeyFail
+	MissingKey PGPMissingKeyPolicy
+
+	// Config 可选的算法配置（摘要算法、对称加密算法等），nil 时使用库的默认值
+	Config *packet.Config
+}
+
+// Transform 实现 Transformer 接口
+func (p *OpenPGP) Transform(m *Message, env *Envelope, entity []byte) ([]byte, error) {
+	if p.Signer == nil && !p.Encrypt {
+		return nil, errors.New("openpgp: signer or encrypt is required")
+	}
+
+	if !p.Encrypt {
+		return p.sign(entity)
+	}
+
+	keys, missing, err := p.recipientKeys(env.Recipients)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(missing) > 0 {
+		switch p.MissingKey {
+		case PGPMissingKeySendPlain:
+			if p.Signer == nil {
+				return entity, nil
+			}
+			return p.sign(entity)
+		case PGPMissingKeySkipRecipient:
+			env.Recipients = removeRecipients(env.Recipients, missing)
+			if len(keys) == 0 {
+				return nil, &PGPMissingKeyError{Recipients: missing}
+			}
+		default:
+			return nil, &PGPMissingKeyError{Recipients: missing}
+		}
+	}
+
+	if err := checkEncryptedBcc(m, env); err != nil {
+		return nil, err
+	}
+
+	return p.encrypt(keys, entity)
+}
+
+// recipientKeys 查找收件人的公钥，返回可用的公钥和缺少公钥的收件人
+//
+// 已吊销、过期或没有加密子密钥的公钥视为缺少
+func (p *OpenPGP) recipientKeys(recipients []string) ([]*openpgp.Entity, []string, error) {
+	if p.Keys == nil {
+		return nil, nil, errors.New("openpgp: keys are required for encryption")
+	}
+
+	now := p.Config.Now()
+
+	var keys []*openpgp.Entity
+	var missing []string
+	for _, rcpt := range recipients {
+		key, err := p.Keys.LookupPGPKey(rcpt)
+		if err != nil {
+			return nil, nil, fmt.Errorf("openpgp: lookup key for %q: %w", rcpt, err)
+		}
+
+		if key == nil {
+			missing = append(missing, rcpt)
+			continue
+		}
+		if _, ok := key.EncryptionKey(now); !ok {
+			missing = append(missing, rcpt)
+			continue
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, missing, nil
+}
+
+// removeRecipients 返回 recipients 中不属于 remove 的地址
+func removeRecipients(recipients, remove []string) []string {
+	result := make([]string, 0, len(recipients))
+	for _, rcpt := range recipients {
+		if !containsFold(remove, rcpt) {
+			result = append(result, rcpt)
+		}
+	}
+	return result
+}
+
+// sign 生成 multipart/signed 实体，entity 原样作为第一部分
+func (p *OpenPGP) sign(entity []byte) ([]byte, error) {
+	var signature bytes.Buffer
+	if err := openpgp.DetachSign(&signature, p.Signer, bytes.NewReader(entity), p.Config); err != nil {
+		return nil, fmt.Errorf("openpgp: sign: %w", err)
+	}
+
+	// micalg 必须与签名实际使用的摘要算法一致，签名算法可能因密钥偏好而与配置不同
+	pkt, err := packet.Read(bytes.NewReader(signature.Bytes()))
+	if err != nil {
+		return nil, fmt.Errorf("openpgp: sign: %w", err)
+	}
+	sig, ok := pkt.(*packet.Signature)
+	if !ok {
+		return nil, errors.New("openpgp: sign: unexpected packet")
+	}
+	micalg, ok := pgpMicalg[sig.Hash]
+	if !ok {
+		return nil, fmt.Errorf("openpgp: sign: unsupported hash %v", sig.Hash)
+	}
+
+	armored, err := armorCRLF("PGP SIGNATURE", signature.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	signaturePart := &mimePart{body: rawBody(armored)}
+	signaturePart.header.add("Content-Type", mime.FormatMediaType("application/pgp-signature", map[string]string{"name": "signature.asc"}))
+	signaturePart.header.add("Content-Description", "OpenPGP digital signature")
+	signaturePart.header.add("Content-Disposition", mime.FormatMediaType(DispositionAttachment, map[string]string{"filename": "signature.asc"}))
+
+	part := newMultipart("signed", &mimePart{raw: entity}, signaturePart)
+	part.header.set("Content-Type", mime.FormatMediaType("multipart/signed", map[string]string{
+		"protocol": "application/pgp-signature",
+		"micalg":   micalg,
+		"boundary": part.boundary,
+	}))
+
+	return renderEntity(part)
+}
+
+// encrypt 生成 multipart/encrypted 实体
+func (p *OpenPGP) encrypt(keys []*openpgp.Entity, entity []byte) ([]byte, error) {
+	var ciphertext bytes.Buffer
+	plaintext, err := openpgp.Encrypt(&ciphertext, keys, p.Signer, &openpgp.FileHints{IsBinary: true}, p.Config)
+	if err != nil {
+		return nil, fmt.Errorf("openpgp: encrypt: %w", err)
+	}
+	if _, err := plaintext.Write(entity); err != nil {
+		return nil, fmt.Errorf("openpgp: encrypt: %w", err)
+	}
+	if err := plaintext.Close(); err != nil {
+		return nil, fmt.Errorf("openpgp: encrypt: %w", err)
+	}
+
+	armored, err := armorCRLF("PGP MESSAGE", ciphertext.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	control := &mimePart{body: rawBody([]byte("Version: 1\r\n"))}
+	control.header.add("Content-Type", "application/pgp-encrypted")
+	control.header.add("Content-Description", "PGP/MIME version identification")
+
+	data := &mimePart{body: rawBody(armored)}
+	data.header.add("Content-Type", mime.FormatMediaType("application/octet-stream", map[string]string{"name": "encrypted.asc"}))
+	data.header.add("Content-Description", "OpenPGP encrypted message")
+	data.header.add("Content-Disposition", mime.FormatMediaType(DispositionInline, map[string]string{"filename": "encrypted.asc"}))
+
+	part := newMultipart("encrypted", control, data)
+	part.header.set("Content-Type", mime.FormatMediaType("multipart/encrypted", map[string]string{
+		"protocol": "application/pgp-encrypted",
+		"boundary": part.boundary,
+	}))
+
+	return renderEntity(part)
+}
+
+// pgpMicalg 是摘要算法对应的 micalg 参数值（RFC 3156 第 5 节、RFC 9580）
+var pgpMicalg = map[crypto.Hash]string{
+	crypto.SHA1:     "pgp-sha1",
+	crypto.SHA224:   "pgp-sha224",
+	crypto.SHA256:   "pgp-sha256",
+	crypto.SHA384:   "pgp-sha384",
+	crypto.SHA512:   "pgp-sha512",
+	crypto.SHA3_256: "pgp-sha3-256",
+	crypto.SHA3_512: "pgp-sha3-512",
+}
+
+// armorCRLF 将数据编码为 ASCII armor，并使用 CRLF 换行
+func armorCRLF(blockType string, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	w, err := armor.Encode(&buf, blockType, nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+
+	return bytes.ReplaceAll(buf.Bytes(), []byte("\n"), crlf), nil
+}
+
+// rawBody 返回原样写出 data 的实体内容函数
+func rawBody(data []byte) func(w io.Writer) error {
+	return func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	}
+}
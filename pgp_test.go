@@ -0,0 +1,264 @@
+package gomailer
+
+import (
+	"bytes"
+	"crypto"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// newPGPEntity 为 address 生成 Ed25519/X25519 密钥
+func newPGPEntity(t *testing.T, address string) *openpgp.Entity {
+	t.Helper()
+
+	e, err := openpgp.NewEntity("", "", address, &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// multipartParts 解析 multipart 实体，返回 Content-Type 参数和每个部分的原始内容
+func multipartParts(t *testing.T, entity []byte, wantType string) (map[string]string, [][]byte) {
+	t.Helper()
+
+	header, body := readEntity(t, entity)
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil || mediaType != wantType {
+		t.Fatalf("Content-Type = %q, want %s", header.Get("Content-Type"), wantType)
+	}
+
+	var parts [][]byte
+	mr := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(part)
+		parts = append(parts, data)
+	}
+
+	return params, parts
+}
+
+// decryptPGP 用 key 解密 multipart/encrypted 实体，返回明文和消息详情
+func decryptPGP(t *testing.T, entity []byte, key *openpgp.Entity) ([]byte, *openpgp.MessageDetails) {
+	t.Helper()
+
+	params, parts := multipartParts(t, entity, "multipart/encrypted")
+	if params["protocol"] != "application/pgp-encrypted" || len(parts) != 2 {
+		t.Fatalf("params = %v, parts = %d", params, len(parts))
+	}
+	if string(parts[0]) != "Version: 1\r\n" {
+		t.Errorf("control part = %q", parts[0])
+	}
+
+	block, err := armor.Decode(bytes.NewReader(parts[1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	md, err := openpgp.ReadMessage(block.Body, openpgp.EntityList{key}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := io.ReadAll(md.UnverifiedBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return plain, md
+}
+
+func TestOpenPGPSign(t *testing.T) {
+	signer := newPGPEntity(t, "sender@example.com")
+
+	tests := []struct {
+		name       string
+		config     *packet.Config
+		wantMicalg string
+	}{
+		{"default", nil, "pgp-sha256"},
+		{"sha512", &packet.Config{DefaultHash: crypto.SHA512}, "pgp-sha512"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &OpenPGP{Signer: signer, Config: tt.config}
+			env := &Envelope{From: "sender@example.com", Recipients: []string{"to@example.com"}}
+
+			signed, err := p.Transform(messageTo("to@example.com"), env, []byte(smimeEntity))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			params, parts := multipartParts(t, signed, "multipart/signed")
+			if params["protocol"] != "application/pgp-signature" || params["micalg"] != tt.wantMicalg {
+				t.Errorf("Content-Type params = %v", params)
+			}
+			if len(parts) != 2 {
+				t.Fatalf("parts = %d, want 2", len(parts))
+			}
+
+			// 第一部分是原样保留的实体（multipart.Reader 会去掉部分的头，这里按分隔行检查原始字节）
+			delimiter := "--" + params["boundary"]
+			if !bytes.Contains(signed, []byte(delimiter+"\r\n"+smimeEntity+"\r\n"+delimiter+"\r\n")) {
+				t.Fatalf("first part is not the original entity: %q", signed)
+			}
+			who, err := openpgp.CheckArmoredDetachedSignature(openpgp.EntityList{signer}, strings.NewReader(smimeEntity), bytes.NewReader(parts[1]), nil)
+			if err != nil {
+				t.Fatalf("signature does not verify: %v", err)
+			}
+			if who.PrimaryKey.KeyId != signer.PrimaryKey.KeyId {
+				t.Errorf("signer = %X", who.PrimaryKey.KeyId)
+			}
+		})
+	}
+}
+
+func TestOpenPGPEncrypt(t *testing.T) {
+	signer := newPGPEntity(t, "sender@example.com")
+	to := newPGPEntity(t, "to@example.com")
+	cc := newPGPEntity(t, "cc@example.com")
+
+	p := &OpenPGP{Signer: signer, Encrypt: true, Keys: PGPKeyring{to, cc}}
+	env := &Envelope{From: "sender@example.com", Recipients: []string{"to@example.com", "CC@example.com"}}
+
+	encrypted, err := p.Transform(messageTo("to@example.com", "cc@example.com"), env, []byte(smimeEntity))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []*openpgp.Entity{to, cc} {
+		plain, md := decryptPGP(t, encrypted, key)
+		if string(plain) != smimeEntity {
+			t.Errorf("decrypted = %q", plain)
+		}
+		if !md.IsSigned || md.SignedByKeyId != signer.PrimaryKey.KeyId || md.SignatureError != nil {
+			t.Errorf("signed = %v, signer = %X, err = %v", md.IsSigned, md.SignedByKeyId, md.SignatureError)
+		}
+		if len(md.EncryptedToKeyIds) != 2 {
+			t.Errorf("encrypted to %d keys, want 2", len(md.EncryptedToKeyIds))
+		}
+	}
+}
+
+func TestOpenPGPMissingKey(t *testing.T) {
+	signer := newPGPEntity(t, "sender@example.com")
+	to := newPGPEntity(t, "to@example.com")
+
+	newEnv := func() *Envelope {
+		return &Envelope{From: "sender@example.com", Recipients: []string{"to@example.com", "nokey@example.com"}}
+	}
+	m := messageTo("to@example.com", "nokey@example.com")
+
+	t.Run("fail", func(t *testing.T) {
+		p := &OpenPGP{Encrypt: true, Keys: PGPKeyring{to}}
+		_, err := p.Transform(m, newEnv(), []byte(smimeEntity))
+
+		var missing *PGPMissingKeyError
+		if !errors.As(err, &missing) || !reflect.DeepEqual(missing.Recipients, []string{"nokey@example.com"}) {
+			t.Fatalf("err = %v, want *PGPMissingKeyError", err)
+		}
+	})
+
+	t.Run("send plain", func(t *testing.T) {
+		env := newEnv()
+		p := &OpenPGP{Encrypt: true, Keys: PGPKeyring{to}, MissingKey: PGPMissingKeySendPlain}
+		plain, err := p.Transform(m, env, []byte(smimeEntity))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(plain) != smimeEntity || len(env.Recipients) != 2 {
+			t.Errorf("entity = %q, recipients = %q, want unchanged", plain, env.Recipients)
+		}
+
+		// 设置了 Signer 时仍然签名
+		p.Signer = signer
+		signed, err := p.Transform(m, newEnv(), []byte(smimeEntity))
+		if err != nil {
+			t.Fatal(err)
+		}
+		multipartParts(t, signed, "multipart/signed")
+	})
+
+	t.Run("skip recipient", func(t *testing.T) {
+		env := newEnv()
+		p := &OpenPGP{Encrypt: true, Keys: PGPKeyring{to}, MissingKey: PGPMissingKeySkipRecipient}
+		encrypted, err := p.Transform(m, env, []byte(smimeEntity))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(env.Recipients, []string{"to@example.com"}) {
+			t.Errorf("recipients = %q, want the recipient without key removed", env.Recipients)
+		}
+
+		plain, md := decryptPGP(t, encrypted, to)
+		if string(plain) != smimeEntity || len(md.EncryptedToKeyIds) != 1 {
+			t.Errorf("decrypted = %q, encrypted to %d keys", plain, len(md.EncryptedToKeyIds))
+		}
+
+		// 没有任何收件人有公钥
+		env = &Envelope{Recipients: []string{"nokey@example.com"}}
+		var missing *PGPMissingKeyError
+		if _, err := p.Transform(m, env, []byte(smimeEntity)); !errors.As(err, &missing) {
+			t.Errorf("err = %v, want *PGPMissingKeyError", err)
+		}
+	})
+}
+
+func TestOpenPGPEncryptRejectsBcc(t *testing.T) {
+	to := newPGPEntity(t, "to@example.com")
+	hidden := newPGPEntity(t, "hidden@example.com")
+
+	m := messageTo("to@example.com")
+	m.Bcc = []mail.Address{{Address: "hidden@example.com"}}
+	newEnv := func() *Envelope {
+		return &Envelope{From: "sender@example.com", Recipients: []string{"to@example.com", "Hidden@example.com"}}
+	}
+
+	p := &OpenPGP{Encrypt: true, Keys: PGPKeyring{to, hidden}}
+	_, err := p.Transform(m, newEnv(), []byte(smimeEntity))
+	if !errors.Is(err, ErrInvalidMessage) {
+		t.Fatalf("err = %v, want ErrInvalidMessage", err)
+	}
+	var errs ValidationErrors
+	if !errors.As(err, &errs) || errs[0].Field != "bcc" {
+		t.Errorf("errors = %v, want a bcc error", err)
+	}
+
+	// Bcc 收件人没有公钥而被跳过时不会出现在密文中
+	p = &OpenPGP{Encrypt: true, Keys: PGPKeyring{to}, MissingKey: PGPMissingKeySkipRecipient}
+	env := newEnv()
+	encrypted, err := p.Transform(m, env, []byte(smimeEntity))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, md := decryptPGP(t, encrypted, to); len(md.EncryptedToKeyIds) != 1 {
+		t.Errorf("encrypted to %d keys, want 1", len(md.EncryptedToKeyIds))
+	}
+
+	// 通过 SMTP 发送时在连接服务器之前失败
+	f := newFakeSMTP(t)
+	client := f.client()
+	client.Transformers = []Transformer{&OpenPGP{Encrypt: true, Keys: PGPKeyring{to, hidden}}}
+	if err := client.Send(m); !errors.Is(err, ErrInvalidMessage) {
+		t.Fatalf("err = %v, want ErrInvalidMessage", err)
+	}
+	if n := f.connections(); n != 0 {
+		t.Errorf("connections = %d, want 0", n)
+	}
+}
@@ -0,0 +1,101 @@
+package gomailer
+
+import (
+	"net/mail"
+	"strings"
+)
+
+// Priority 定义了邮件的优先级
+//
+// 不同客户端读取不同的邮件头（Outlook 读取 Importance 和 X-Priority，
+// Thunderbird 读取 X-Priority），因此设置优先级时会同时生成
+// X-Priority、Importance 和 Priority（RFC 2156）三个邮件头，取值保持一致
+type Priority string
+
+const (
+	// PriorityLow 低优先级
+	PriorityLow Priority = "low"
+
+	// PriorityNormal 普通优先级（显式生成普通优先级的邮件头）
+	PriorityNormal Priority = "normal"
+
+	// PriorityHigh 高优先级，Outlook 和 Thunderbird 会显示高优先级标记
+	PriorityHigh Priority = "high"
+
+	// PriorityUrgent 紧急，与 PriorityHigh 相同但 X-Priority 为最高级别 1
+	PriorityUrgent Priority = "urgent"
+)
+
+// priorityHeader 是一个优先级对应的邮件头取值
+type priorityHeader struct {
+	xPriority  string
+	importance string
+	priority   string
+}
+
+// priorityHeaders 是各优先级生成的邮件头
+var priorityHeaders = map[Priority]priorityHeader{
+	PriorityLow:    {"5 (Lowest)", "low", "non-urgent"},
+	PriorityNormal: {"3 (Normal)", "normal", "normal"},
+	PriorityHigh:   {"2 (High)", "high", "urgent"},
+	PriorityUrgent: {"1 (Highest)", "high", "urgent"},
+}
+
+// valid 检查优先级是否为已定义的值（空字符串表示不设置）
+func (p Priority) valid() bool {
+	if p == "" {
+		return true
+	}
+	_, ok := priorityHeaders[p]
+	return ok
+}
+
+// addTo 将优先级对应的邮件头写入 h，未设置优先级时不写入
+func (p Priority) addTo(h *mailHeader) {
+	values, ok := priorityHeaders[p]
+	if !ok {
+		return
+	}
+
+	h.add("X-Priority", values.xPriority)
+	h.add("Importance", values.importance)
+	h.add("Priority", values.priority)
+}
+
+// parsePriority 根据 X-Priority、Importance 或 Priority 邮件头推断优先级，都不存在时返回空字符串
+func parsePriority(header mail.Header) Priority {
+	if value := strings.TrimSpace(header.Get("X-Priority")); value != "" {
+		switch value[0] {
+		case '1':
+			return PriorityUrgent
+		case '2':
+			return PriorityHigh
+		case '3':
+			return PriorityNormal
+		case '4', '5':
+			return PriorityLow
+		}
+	}
+
+	if value := strings.ToLower(strings.TrimSpace(header.Get("Importance"))); value != "" {
+		switch value {
+		case "high":
+			return PriorityHigh
+		case "normal":
+			return PriorityNormal
+		case "low":
+			return PriorityLow
+		}
+	}
+
+	switch strings.ToLower(strings.TrimSpace(header.Get("Priority"))) {
+	case "urgent":
+		return PriorityHigh
+	case "normal":
+		return PriorityNormal
+	case "non-urgent":
+		return PriorityLow
+	}
+
+	return ""
+}
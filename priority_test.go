@@ -0,0 +1,79 @@
+package gomailer
+
+import (
+	"errors"
+	"net/mail"
+	"testing"
+)
+
+func TestPriorityHeaders(t *testing.T) {
+	tests := []struct {
+		priority                       Priority
+		xPriority, importance, urgency string
+	}{
+		{"", "", "", ""},
+		{PriorityLow, "5 (Lowest)", "low", "non-urgent"},
+		{PriorityNormal, "3 (Normal)", "normal", "normal"},
+		{PriorityHigh, "2 (High)", "high", "urgent"},
+		{PriorityUrgent, "1 (Highest)", "high", "urgent"},
+	}
+
+	for _, tt := range tests {
+		name := string(tt.priority)
+		if name == "" {
+			name = "unset"
+		}
+
+		t.Run(name, func(t *testing.T) {
+			m := messageTo("to@example.com")
+			m.Priority = tt.priority
+
+			h := readHeader(t, string(renderBytes(t, m)))
+			for _, c := range []struct{ key, want string }{
+				{"X-Priority", tt.xPriority},
+				{"Importance", tt.importance},
+				{"Priority", tt.urgency},
+			} {
+				if values := h.Values(c.key); c.want == "" && len(values) != 0 {
+					t.Errorf("%s = %q, want no header", c.key, values)
+				} else if c.want != "" && (len(values) != 1 || values[0] != c.want) {
+					t.Errorf("%s = %q, want %q", c.key, values, c.want)
+				}
+			}
+
+			if got := reparse(t, m).Priority; got != tt.priority {
+				t.Errorf("parsed Priority = %q, want %q", got, tt.priority)
+			}
+		})
+	}
+}
+
+func TestParsePriority(t *testing.T) {
+	tests := []struct {
+		header mail.Header
+		want   Priority
+	}{
+		{mail.Header{"X-Priority": {"4"}}, PriorityLow},
+		{mail.Header{"X-Priority": {"1"}, "Importance": {"low"}}, PriorityUrgent},
+		{mail.Header{"Importance": {"High"}}, PriorityHigh},
+		{mail.Header{"Priority": {"non-urgent"}}, PriorityLow},
+		{mail.Header{"X-Priority": {"unknown"}}, ""},
+	}
+
+	for _, tt := range tests {
+		if got := parsePriority(tt.header); got != tt.want {
+			t.Errorf("parsePriority(%v) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestPriorityValidate(t *testing.T) {
+	m := messageTo("to@example.com")
+	m.Priority = "highest"
+
+	err := m.Validate()
+	var errs ValidationErrors
+	if !errors.As(err, &errs) || errs[0].Field != "priority" {
+		t.Errorf("err = %v, want a priority error", err)
+	}
+}
@@ -0,0 +1,326 @@
+package gomailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 确保 RateLimitedMailer 实现了 Mailer 和 ContextMailer 接口
+var (
+	_ Mailer        = (*RateLimitedMailer)(nil)
+	_ ContextMailer = (*RateLimitedMailer)(nil)
+)
+
+// ErrRateLimited 表示发送请求超出了限流配置
+//
+// RateLimitedMailer 在非阻塞模式下返回的 *RateLimitError 满足 errors.Is(err, ErrRateLimited)
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// RateLimitError 描述了一次被限流拒绝的发送
+type RateLimitError struct {
+	// Domain 触发限流的收件人域名，为空表示触发的是全局限流
+	Domain string
+
+	// RetryAfter 预计需要等待多久才能再次发送
+	RetryAfter time.Duration
+}
+
+// Error 实现 error 接口
+func (e *RateLimitError) Error() string {
+	if e.Domain == "" {
+		return fmt.Sprintf("rate limit exceeded, retry after %s", e.RetryAfter)
+	}
+	return fmt.Sprintf("rate limit exceeded for domain %q, retry after %s", e.Domain, e.RetryAfter)
+}
+
+// Is 使 errors.Is(err, ErrRateLimited) 返回 true
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// RateLimit 定义了一个令牌桶限流配置
+type RateLimit struct {
+	// Rate 每秒允许发送的邮件数，小于等于 0 表示不限流
+	Rate float64
+
+	// Burst 允许的突发数量（令牌桶容量）
+	// 如果未明确设置，默认为 Rate 向上取整（至少为 1）
+	Burst int
+}
+
+// enabled 返回是否启用了该限流配置
+func (l RateLimit) enabled() bool {
+	return l.Rate > 0
+}
+
+// RateLimitedMailer 是一个为其它 Mailer 增加发送频率限制的包装器
+//
+// 支持一个全局限流以及按收件人域名的限流，每封邮件消耗一个全局令牌，
+// 并为其收件人（To/Cc/Bcc）涉及的每个不同域名各消耗一个令牌
+//
+// 示例:
+//
+//	mailer := &RateLimitedMailer{
+//		Mailer: client,
+//		Global: RateLimit{Rate: 14},
+//		Domains: map[string]RateLimit{
+//			"gmail.com": {Rate: 2, Burst: 5},
+//		},
+//	}
+type RateLimitedMailer struct {
+	// Mailer 实际执行发送的邮件客户端
+	Mailer Mailer
+
+	// Global 全局限流配置，零值表示不限制
+	Global RateLimit
+
+	// Domains 按收件人域名（小写）配置的限流
+	Domains map[string]RateLimit
+
+	// DefaultDomain 未在 Domains 中列出的域名使用的限流配置，零值表示不限制
+	DefaultDomain RateLimit
+
+	// NonBlocking 为 true 时，超出限流直接返回 *RateLimitError，
+	// 否则阻塞等待直到获得令牌或 context 结束；需要等待的时间超过 context 的截止时间时
+	// 同样立即返回 *RateLimitError，不会占用令牌
+	NonBlocking bool
+
+	mu      sync.Mutex
+	global  *tokenBucket
+	buckets map[string]*tokenBucket
+}
+
+// Send 实现 Mailer 接口
+// 等价于 SendContext(context.Background(), m)
+func (r *RateLimitedMailer) Send(m *Message) error {
+	return r.SendContext(context.Background(), m)
+}
+
+// SendContext 实现 ContextMailer 接口
+// 在获得所有相关令牌后才调用被包装的 Mailer 发送邮件
+//
+// 参数:
+//   - ctx: 控制等待令牌过程的 context
+//   - m: 要发送的邮件消息
+//
+// 返回:
+//   - error: 被限流、ctx 结束或发送失败时返回错误
+func (r *RateLimitedMailer) SendContext(ctx context.Context, m *Message) error {
+	return r.sendVia(ctx, r.Mailer, m)
+}
+
+// sendVia 获得令牌后通过 target（被包装的 Mailer 或其会话）发送邮件
+func (r *RateLimitedMailer) sendVia(ctx context.Context, target Mailer, m *Message) error {
+	if r.Mailer == nil {
+		return errors.New("rate limited mailer has no underlying mailer")
+	}
+
+	if err := r.wait(ctx, m); err != nil {
+		return err
+	}
+
+	return sendWithContext(ctx, target, m)
+}
+
+// wait 为邮件获取所需的全部令牌
+func (r *RateLimitedMailer) wait(ctx context.Context, m *Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// 查找和扣除令牌在同一次加锁中完成，避免配置与令牌状态在两次加锁之间发生变化
+	r.mu.Lock()
+
+	buckets, domains := r.bucketsFor(m)
+	if len(buckets) == 0 {
+		r.mu.Unlock()
+		return nil
+	}
+
+	now := time.Now()
+
+	if r.NonBlocking {
+		// 只有所有桶都有可用令牌时才一并扣除，避免部分扣除造成令牌泄漏
+		for i, b := range buckets {
+			if wait := b.available(now); wait > 0 {
+				r.mu.Unlock()
+				return &RateLimitError{Domain: domains[i], RetryAfter: wait}
+			}
+		}
+		for _, b := range buckets {
+			b.reserve(now)
+		}
+		r.mu.Unlock()
+		return nil
+	}
+
+	var delay time.Duration
+	var domain string
+	for i, b := range buckets {
+		if wait := b.reserve(now); wait > delay {
+			delay, domain = wait, domains[i]
+		}
+	}
+
+	// 等到截止时间也拿不到令牌时立即失败，并归还预留的令牌
+	if deadline, ok := ctx.Deadline(); ok && delay > 0 && now.Add(delay).After(deadline) {
+		for _, b := range buckets {
+			b.cancel()
+		}
+		r.mu.Unlock()
+		return &RateLimitError{Domain: domain, RetryAfter: delay}
+	}
+	r.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// 归还预留的令牌，使其它等待者不受影响
+		r.mu.Lock()
+		for _, b := range buckets {
+			b.cancel()
+		}
+		r.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// bucketsFor 返回发送 m 需要消耗令牌的所有桶及其对应的域名（全局桶对应空字符串），调用方负责加锁
+func (r *RateLimitedMailer) bucketsFor(m *Message) ([]*tokenBucket, []string) {
+	var buckets []*tokenBucket
+	var domains []string
+
+	if r.Global.enabled() {
+		if r.global == nil {
+			r.global = newTokenBucket(r.Global)
+		}
+		buckets = append(buckets, r.global)
+		domains = append(domains, "")
+	}
+
+	if m == nil {
+		return buckets, domains
+	}
+
+	for _, domain := range recipientDomains(m) {
+		limit, ok := r.Domains[domain]
+		if !ok {
+			limit = r.DefaultDomain
+		}
+		if !limit.enabled() {
+			continue
+		}
+
+		if r.buckets == nil {
+			r.buckets = map[string]*tokenBucket{}
+		}
+		b := r.buckets[domain]
+		if b == nil {
+			b = newTokenBucket(limit)
+			r.buckets[domain] = b
+		}
+		buckets = append(buckets, b)
+		domains = append(domains, domain)
+	}
+
+	return buckets, domains
+}
+
+// recipientDomains 返回邮件所有收件人（To/Cc/Bcc）去重后的小写域名列表
+func recipientDomains(m *Message) []string {
+	var domains []string
+	seen := map[string]bool{}
+
+	for _, list := range [][]mail.Address{m.To, m.Cc, m.Bcc} {
+		for _, addr := range list {
+			at := strings.LastIndex(addr.Address, "@")
+			if at < 0 {
+				continue
+			}
+			domain := strings.ToLower(addr.Address[at+1:])
+			if domain == "" || seen[domain] {
+				continue
+			}
+			seen[domain] = true
+			domains = append(domains, domain)
+		}
+	}
+
+	return domains
+}
+
+// -------------------------------------------------------------------
+// 令牌桶实现
+// -------------------------------------------------------------------
+
+// tokenBucket 是一个简单的令牌桶，调用方负责加锁
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket 根据限流配置创建一个装满令牌的桶
+func newTokenBucket(limit RateLimit) *tokenBucket {
+	burst := float64(limit.Burst)
+	if burst <= 0 {
+		burst = math.Max(1, math.Ceil(limit.Rate))
+	}
+
+	return &tokenBucket{
+		rate:   limit.Rate,
+		burst:  burst,
+		tokens: burst,
+	}
+}
+
+// refill 根据流逝的时间补充令牌
+func (b *tokenBucket) refill(now time.Time) {
+	if !b.last.IsZero() && now.After(b.last) {
+		b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	b.last = now
+}
+
+// available 返回获得一个令牌还需等待的时长，0 表示当前即有可用令牌
+func (b *tokenBucket) available(now time.Time) time.Duration {
+	b.refill(now)
+
+	if b.tokens >= 1 {
+		return 0
+	}
+
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// reserve 扣除一个令牌（允许透支）并返回需要等待的时长
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.refill(now)
+	b.tokens--
+
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// cancel 归还一个之前通过 reserve 扣除的令牌
+func (b *tokenBucket) cancel() {
+	b.tokens = math.Min(b.burst, b.tokens+1)
+}
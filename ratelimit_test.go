@@ -0,0 +1,177 @@
+package gomailer
+
+import (
+	"context"
+	"errors"
+	"net/mail"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingMailer 记录收到的邮件，err 不为空时返回该错误
+type recordingMailer struct {
+	mu   sync.Mutex
+	sent []*Message
+	err  error
+}
+
+func (r *recordingMailer) Send(m *Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, m)
+	return r.err
+}
+
+// count 返回已收到的邮件数
+func (r *recordingMailer) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.sent)
+}
+
+// messageTo 创建发送给指定地址的邮件
+func messageTo(addrs ...string) *Message {
+	m := &Message{From: mail.Address{Address: "sender@example.com"}, Text: "hello"}
+	for _, addr := range addrs {
+		m.To = append(m.To, mail.Address{Address: addr})
+	}
+	return m
+}
+
+func TestRateLimitedMailerGlobalLimit(t *testing.T) {
+	inner := &recordingMailer{}
+	r := &RateLimitedMailer{Mailer: inner, Global: RateLimit{Rate: 1, Burst: 2}, NonBlocking: true}
+
+	for i := 0; i < 2; i++ {
+		if err := r.Send(messageTo("a@example.com")); err != nil {
+			t.Fatalf("send %d: %v", i, err)
+		}
+	}
+
+	err := r.Send(messageTo("b@example.org"))
+	var limitErr *RateLimitError
+	if !errors.As(err, &limitErr) || !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("err = %v, want *RateLimitError", err)
+	}
+	if limitErr.Domain != "" || limitErr.RetryAfter <= 0 || limitErr.RetryAfter > time.Second {
+		t.Errorf("err = %+v, want global limit with RetryAfter in (0, 1s]", limitErr)
+	}
+	if n := inner.count(); n != 2 {
+		t.Errorf("sent = %d, want 2", n)
+	}
+}
+
+func TestRateLimitedMailerDomainLimit(t *testing.T) {
+	inner := &recordingMailer{}
+	r := &RateLimitedMailer{
+		Mailer:      inner,
+		Domains:     map[string]RateLimit{"gmail.com": {Rate: 1, Burst: 1}},
+		NonBlocking: true,
+	}
+
+	if err := r.Send(messageTo("a@Gmail.com")); err != nil {
+		t.Fatal(err)
+	}
+
+	var limitErr *RateLimitError
+	if err := r.Send(messageTo("b@gmail.com")); !errors.As(err, &limitErr) || limitErr.Domain != "gmail.com" {
+		t.Fatalf("err = %v, want limit for gmail.com", err)
+	}
+
+	// 没有配置限流的域名不受影响
+	for i := 0; i < 5; i++ {
+		if err := r.Send(messageTo("c@example.com")); err != nil {
+			t.Fatalf("send %d: %v", i, err)
+		}
+	}
+
+	// 同时发往受限域名的邮件被整体拒绝
+	if err := r.Send(messageTo("c@example.com", "d@gmail.com")); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("err = %v, want ErrRateLimited", err)
+	}
+	if n := inner.count(); n != 6 {
+		t.Errorf("sent = %d, want 6", n)
+	}
+}
+
+func TestRateLimitedMailerBlocks(t *testing.T) {
+	inner := &recordingMailer{}
+	r := &RateLimitedMailer{Mailer: inner, Global: RateLimit{Rate: 20, Burst: 1}}
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := r.Send(messageTo("a@example.com")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 第一封使用初始令牌，之后每封等待 50ms
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("3 sends at 20/s took %s, want at least 100ms", elapsed)
+	}
+	if n := inner.count(); n != 3 {
+		t.Errorf("sent = %d, want 3", n)
+	}
+}
+
+func TestRateLimitedMailerContext(t *testing.T) {
+	t.Run("deadline shorter than wait", func(t *testing.T) {
+		inner := &recordingMailer{}
+		r := &RateLimitedMailer{Mailer: inner, Global: RateLimit{Rate: 0.1, Burst: 1}}
+		if err := r.Send(messageTo("a@example.com")); err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		start := time.Now()
+		err := r.SendContext(ctx, messageTo("a@example.com"))
+		if !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("err = %v, want ErrRateLimited", err)
+		}
+		if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+			t.Errorf("returned after %s, want immediately", elapsed)
+		}
+		if tokens := r.global.tokens; tokens < -0.5 {
+			t.Errorf("tokens = %f, reservation was not returned", tokens)
+		}
+		if n := inner.count(); n != 1 {
+			t.Errorf("sent = %d, want 1", n)
+		}
+	})
+
+	t.Run("cancelled while waiting", func(t *testing.T) {
+		inner := &recordingMailer{}
+		r := &RateLimitedMailer{Mailer: inner, Global: RateLimit{Rate: 0.1, Burst: 1}}
+		if err := r.Send(messageTo("a@example.com")); err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(20*time.Millisecond, cancel)
+
+		if err := r.SendContext(ctx, messageTo("a@example.com")); !errors.Is(err, context.Canceled) {
+			t.Fatalf("err = %v, want context.Canceled", err)
+		}
+		if tokens := r.global.tokens; tokens < -0.5 {
+			t.Errorf("tokens = %f, reservation was not returned", tokens)
+		}
+	})
+
+	t.Run("already cancelled", func(t *testing.T) {
+		inner := &recordingMailer{}
+		r := &RateLimitedMailer{Mailer: inner, Global: RateLimit{Rate: 1}}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		if err := r.SendContext(ctx, messageTo("a@example.com")); !errors.Is(err, context.Canceled) {
+			t.Fatalf("err = %v, want context.Canceled", err)
+		}
+		if n := inner.count(); n != 0 {
+			t.Errorf("sent = %d, want 0", n)
+		}
+	})
+}
@@ -0,0 +1,277 @@
+package gomailer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// RawMessage 是一封已经渲染完成的邮件（如已签名的 MIME 内容），发送时内容保持原样
+//
+// 与 Message 不同，RawMessage 的信封（发件人和收件人）与邮件头相互独立，
+// 收件人只由 To 决定，Body 中的 To/Cc/Bcc 邮件头不会被读取
+type RawMessage struct {
+	// From 信封发件人（MAIL FROM），为空时使用空的反向路径 "<>"（通常用于退信）
+	From string
+
+	// To 信封收件人（RCPT TO）
+	To []string
+
+	// Body 完整的邮件内容（邮件头 + 空行 + 正文），使用 CRLF 或 LF 换行
+	//
+	// 实现了 io.Seeker 的 Body（如 *os.File、*bytes.Reader）会被流式发送，
+	// 其它 Reader 会先读入内存，以便计算大小和向多批收件人重复发送
+	Body io.Reader
+}
+
+// Validate 检查原始邮件的信封是否可以发送
+//
+// 返回:
+//   - error: 没有问题时返回 nil，否则返回 ValidationErrors
+func (m *RawMessage) Validate() error {
+	var errs ValidationErrors
+
+	if m == nil {
+		errs.add("", "message is nil")
+		return errs
+	}
+
+	if m.From != "" && !validEnvelopeAddress(m.From) {
+		errs.add("from", "invalid envelope address %q", m.From)
+	}
+
+	if len(m.To) == 0 {
+		errs.add("to", "at least one recipient is required")
+	}
+
+	seen := map[string]bool{}
+	for i, rcpt := range m.To {
+		field := fmt.Sprintf("to[%d]", i)
+		if !validEnvelopeAddress(rcpt) {
+			errs.add(field, "invalid envelope address %q", rcpt)
+			continue
+		}
+
+		key := strings.ToLower(rcpt)
+		if seen[key] {
+			errs.add(field, "duplicate recipient %q", rcpt)
+		}
+		seen[key] = true
+	}
+
+	if m.Body == nil {
+		errs.add("body", "body is required")
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// validEnvelopeAddress 检查信封地址：必须是合法的 addr-spec，
+// 并且不能以 "-" 开头（避免被 sendmail 当作命令行选项）
+func validEnvelopeAddress(address string) bool {
+	return validAddress(address) && !strings.HasPrefix(address, "-")
+}
+
+// RawMailer 是一个可选接口，由支持发送原始邮件的传输实现
+type RawMailer interface {
+	// SendRaw 原样发送已经渲染完成的邮件
+	SendRaw(m *RawMessage) error
+}
+
+// SendRawEvent 原始邮件发送事件
+type SendRawEvent struct {
+	Event
+	// Message 正在发送的原始邮件
+	Message *RawMessage
+}
+
+// rawContent 是 RawMessage.Body 的 messageContent 实现
+type rawContent struct {
+	// seeker Body 实现了 io.Seeker 时用于重复读取
+	seeker io.ReadSeeker
+	offset int64
+
+	// buffered 无法重复读取的 Body 缓存在内存中
+	buffered *bytes.Buffer
+
+	size int64
+}
+
+// newRawContent 根据 Body 创建可以重复写出的内容
+func newRawContent(body io.Reader) (*rawContent, error) {
+	if s, ok := body.(io.ReadSeeker); ok {
+		offset, err := s.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+		return &rawContent{seeker: s, offset: offset}, nil
+	}
+
+	buffered := &bytes.Buffer{}
+	if _, err := buffered.ReadFrom(body); err != nil {
+		return nil, err
+	}
+
+	return &rawContent{buffered: buffered, size: int64(buffered.Len())}, nil
+}
+
+// Size 实现 messageContent 接口
+func (r *rawContent) Size() (int64, error) {
+	if r.size > 0 || r.buffered != nil {
+		return r.size, nil
+	}
+
+	end, err := r.seeker.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+
+	r.size = end - r.offset
+	return r.size, nil
+}
+
+// knownSize 实现 messageContent 接口
+func (r *rawContent) knownSize() int64 {
+	return r.size
+}
+
+// use8BitMIME 实现 messageContent 接口
+//
+// 原始邮件的内容不会被修改；无法预先检查的流式内容按包含 8bit 数据处理
+func (r *rawContent) use8BitMIME() bool {
+	if r.buffered == nil {
+		return true
+	}
+
+	for _, b := range r.buffered.Bytes() {
+		if b >= 0x80 {
+			return true
+		}
+	}
+	return false
+}
+
+// deliveryNotification 实现 messageContent 接口，原始邮件不支持投递状态通知
+func (r *rawContent) deliveryNotification() *DeliveryNotification {
+	return nil
+}
+
+// WriteTo 实现 io.WriterTo 接口，可以多次调用
+func (r *rawContent) WriteTo(w io.Writer) (int64, error) {
+	if r.buffered != nil {
+		n, err := w.Write(r.buffered.Bytes())
+		return int64(n), err
+	}
+
+	if _, err := r.seeker.Seek(r.offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	return io.Copy(w, r.seeker)
+}
+
+// -------------------------------------------------------------------
+// 传输实现
+// -------------------------------------------------------------------
+
+// 确保 SMTPClient 和 Sendmail 实现了 RawMailer 接口
+var (
+	_ RawMailer = (*SMTPClient)(nil)
+	_ RawMailer = (*Sendmail)(nil)
+)
+
+// OnSendRaw 返回原始邮件的发送钩子，用法与 OnSend 相同
+func (c *SMTPClient) OnSendRaw() *Hook[*SendRawEvent] {
+	if c.onSendRaw == nil {
+		c.onSendRaw = &Hook[*SendRawEvent]{}
+	}
+	return c.onSendRaw
+}
+
+// SendRaw 实现 RawMailer 接口
+// 通过 SMTP 原样发送已经渲染完成的邮件
+//
+// 除 SMTP 协议要求的 CRLF 换行和行首点号转义外，Body 不会被修改，
+// 因此签名（DKIM、S/MIME 等）保持有效。MaxRecipientsPerMessage、MaxMessageSize
+// 和服务器的 SIZE 扩展同样生效
+//
+// 参数:
+//   - m: 要发送的原始邮件
+//
+// 返回:
+//   - error: 发送失败时返回错误，部分收件人失败时返回 *DeliveryError
+func (c *SMTPClient) SendRaw(m *RawMessage) error {
+	if c.onSendRaw != nil {
+		return c.onSendRaw.Trigger(&SendRawEvent{Message: m}, func(e *SendRawEvent) error {
+			return c.sendRaw(e.Message)
+		})
+	}
+
+	return c.sendRaw(m)
+}
+
+// sendRaw 内部发送方法，执行实际的原始邮件发送
+func (c *SMTPClient) sendRaw(m *RawMessage) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	body, err := newRawContent(m.Body)
+	if err != nil {
+		return err
+	}
+
+	return c.deliver(m.From, m.To, body)
+}
+
+// OnSendRaw 返回原始邮件的发送钩子，用法与 OnSend 相同
+func (c *Sendmail) OnSendRaw() *Hook[*SendRawEvent] {
+	if c.onSendRaw == nil {
+		c.onSendRaw = &Hook[*SendRawEvent]{}
+	}
+	return c.onSendRaw
+}
+
+// SendRaw 实现 RawMailer 接口
+// 通过 sendmail 命令原样发送已经渲染完成的邮件
+//
+// 信封发件人通过 -f 传递；使用 -i 使单独一行的 "." 不会被当作输入结束
+//
+// 参数:
+//   - m: 要发送的原始邮件
+//
+// 返回:
+//   - error: 发送失败时返回错误，成功返回 nil
+func (c *Sendmail) SendRaw(m *RawMessage) error {
+	if c.onSendRaw != nil {
+		return c.onSendRaw.Trigger(&SendRawEvent{Message: m}, func(e *SendRawEvent) error {
+			return c.sendRaw(e.Message)
+		})
+	}
+
+	return c.sendRaw(m)
+}
+
+// sendRaw 内部发送方法，执行实际的 sendmail 调用
+func (c *Sendmail) sendRaw(m *RawMessage) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	body, err := newRawContent(m.Body)
+	if err != nil {
+		return err
+	}
+
+	args, err := sendmailArgs(m.From, m.To)
+	if err != nil {
+		return err
+	}
+
+	return c.pipe(args, body)
+}
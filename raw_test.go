@@ -0,0 +1,182 @@
+package gomailer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// signedRawBody 是一封已签名的邮件：信封收件人与 To 邮件头不同，包含行首点号和 8bit 内容
+const signedRawBody = "From: sender@example.com\r\n" +
+	"To: list@example.com\r\n" +
+	"Subject: signed\r\n" +
+	"DKIM-Signature: v=1; a=rsa-sha256; d=example.com; b=abc\r\n" +
+	"\r\n" +
+	"Grüße\r\n" +
+	".leading dot\r\n" +
+	".\r\n" +
+	"end\r\n"
+
+func TestSMTPClientSendRaw(t *testing.T) {
+	f := newFakeSMTP(t, "8BITMIME")
+	client := f.client()
+
+	var hooked []*RawMessage
+	client.OnSendRaw().BindFunc(func(e *SendRawEvent) error {
+		hooked = append(hooked, e.Message)
+		return e.Next()
+	})
+
+	m := &RawMessage{
+		From: "bounce@example.com",
+		To:   []string{"a@example.com", "b@example.org"},
+		Body: strings.NewReader(signedRawBody),
+	}
+	if err := client.SendRaw(m); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(hooked) != 1 || hooked[0] != m {
+		t.Errorf("OnSendRaw calls = %v", hooked)
+	}
+
+	txns := f.transactions()
+	if len(txns) != 1 {
+		t.Fatalf("transactions = %d, want 1", len(txns))
+	}
+	txn := txns[0]
+	if txn.From != "bounce@example.com" {
+		t.Errorf("MAIL FROM = %q, want envelope sender", txn.From)
+	}
+	if want := []string{"a@example.com", "b@example.org"}; !reflect.DeepEqual(txn.Rcpts, want) {
+		t.Errorf("RCPT TO = %q, want %q", txn.Rcpts, want)
+	}
+	if !reflect.DeepEqual(txn.FromParams, []string{"BODY=8BITMIME"}) {
+		t.Errorf("MAIL FROM params = %q", txn.FromParams)
+	}
+
+	// 服务器收到的内容去掉点号转义后与原文相同（ReadDotBytes 会把 CRLF 转换为 LF）
+	if want := strings.ReplaceAll(signedRawBody, "\r\n", "\n"); txn.Data != want {
+		t.Errorf("data = %q, want %q", txn.Data, want)
+	}
+}
+
+func TestSMTPClientSendRawNullSenderAndOffset(t *testing.T) {
+	f := newFakeSMTP(t)
+
+	// Body 从当前位置开始发送
+	body := strings.NewReader("garbage" + signedRawBody)
+	body.Seek(int64(len("garbage")), io.SeekStart)
+
+	if err := f.client().SendRaw(&RawMessage{To: []string{"a@example.com"}, Body: body}); err != nil {
+		t.Fatal(err)
+	}
+
+	txns := f.transactions()
+	if len(txns) != 1 || txns[0].From != "" {
+		t.Fatalf("transactions = %+v, want null sender", txns)
+	}
+	if !strings.HasPrefix(txns[0].Data, "From: sender@example.com\n") {
+		t.Errorf("data = %q", txns[0].Data)
+	}
+}
+
+func TestSendRawHookCanAbort(t *testing.T) {
+	f := newFakeSMTP(t)
+	client := f.client()
+
+	stop := errors.New("blocked by policy")
+	client.OnSendRaw().BindFunc(func(e *SendRawEvent) error {
+		return stop
+	})
+
+	err := client.SendRaw(&RawMessage{To: []string{"a@example.com"}, Body: strings.NewReader(signedRawBody)})
+	if !errors.Is(err, stop) {
+		t.Fatalf("err = %v, want hook error", err)
+	}
+	if n := f.connections(); n != 0 {
+		t.Errorf("connections = %d, want 0", n)
+	}
+}
+
+func TestRawMessageValidate(t *testing.T) {
+	body := func() io.Reader { return strings.NewReader(signedRawBody) }
+
+	tests := []struct {
+		name      string
+		m         *RawMessage
+		wantField string
+	}{
+		{"nil", nil, ""},
+		{"no recipients", &RawMessage{From: "a@example.com", Body: body()}, "to"},
+		{"invalid sender", &RawMessage{From: "not an address", To: []string{"b@example.com"}, Body: body()}, "from"},
+		{"option-like sender", &RawMessage{From: "-oQ@example.com", To: []string{"b@example.com"}, Body: body()}, "from"},
+		{"invalid recipient", &RawMessage{To: []string{"b@example.com", "<b@example.com>"}, Body: body()}, "to[1]"},
+		{"option-like recipient", &RawMessage{To: []string{"-X/tmp/log@example.com"}, Body: body()}, "to[0]"},
+		{"duplicate recipient", &RawMessage{To: []string{"b@example.com", "B@example.com"}, Body: body()}, "to[1]"},
+		{"no body", &RawMessage{To: []string{"b@example.com"}}, "body"},
+	}
+
+	f := newFakeSMTP(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := f.client().SendRaw(tt.m)
+			if !errors.Is(err, ErrInvalidMessage) {
+				t.Fatalf("err = %v, want ErrInvalidMessage", err)
+			}
+
+			var errs ValidationErrors
+			if !errors.As(err, &errs) || errs[0].Field != tt.wantField {
+				t.Errorf("errors = %v, want field %q", err, tt.wantField)
+			}
+		})
+	}
+
+	if n := f.connections(); n != 0 {
+		t.Errorf("connections = %d, invalid messages must not be sent", n)
+	}
+}
+
+func TestSendmailSendRaw(t *testing.T) {
+	argsFile, bodyFile := installFakeSendmail(t)
+
+	var hooked int
+	client := &Sendmail{}
+	client.OnSendRaw().BindFunc(func(e *SendRawEvent) error {
+		hooked++
+		return e.Next()
+	})
+
+	// 不能 Seek 的 Body 会先读入内存
+	m := &RawMessage{
+		From: "bounce@example.com",
+		To:   []string{"a@example.com", "b@example.org"},
+		Body: io.MultiReader(strings.NewReader(signedRawBody)),
+	}
+	if err := client.SendRaw(m); err != nil {
+		t.Fatal(err)
+	}
+	if hooked != 1 {
+		t.Errorf("OnSendRaw calls = %d, want 1", hooked)
+	}
+
+	args, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Fields(string(args)), []string{"-i", "-f", "bounce@example.com", "a@example.com", "b@example.org"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("args = %q, want %q", got, want)
+	}
+
+	body, err := os.ReadFile(bodyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(body, []byte(signedRawBody)) {
+		t.Errorf("body = %q, want the raw bytes unchanged", body)
+	}
+}
@@ -0,0 +1,606 @@
+package gomailer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// reservedHeaders 由渲染器根据邮件结构自动生成，不允许通过 Message.Headers 设置
+var reservedHeaders = []string{
+	"MIME-Version",
+	"Content-Type",
+	"Content-Transfer-Encoding",
+}
+
+// isReservedHeader 检查字段名是否为保留的 MIME 结构字段
+func isReservedHeader(name string) bool {
+	for _, r := range reservedHeaders {
+		if strings.EqualFold(r, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildHeader 根据邮件消息生成顶层邮件头，messageID 为写入 Message-ID 的值（不含尖括号）
+//
+// 所有用户提供的内容都会先经过头部注入检查再编码，Bcc 永远不会写入邮件头；
+// Headers 中的自定义字段会替换同名的标准字段
+func buildHeader(m *Message, messageID string) (*mailHeader, error) {
+	h := &mailHeader{}
+
+	cs, err := lookupCharset(m.Charset)
+	if err != nil {
+		return nil, err
+	}
+
+	// 地址字段
+	addressFields := []struct {
+		name  string
+		addrs []mail.Address
+	}{
+		{"From", []mail.Address{m.From}},
+		{"Sender", nonEmptyAddress(m.Sender)},
+		{"Reply-To", m.ReplyTo},
+		{"To", m.To},
+		{"Cc", m.Cc},
+		{"Disposition-Notification-To", m.ReadReceiptTo},
+	}
+	for _, f := range addressFields {
+		if len(f.addrs) == 0 {
+			continue
+		}
+		if err := checkAddressHeader(f.name, f.addrs...); err != nil {
+			return nil, err
+		}
+		value, err := cs.addressList(f.addrs)
+		if err != nil {
+			return nil, fmt.Errorf("header %q: %w", f.name, err)
+		}
+		h.add(f.name, value)
+	}
+
+	// 主题
+	if err := checkHeaderValue("Subject", m.Subject); err != nil {
+		return nil, err
+	}
+	subject, err := cs.headerText(m.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("header %q: %w", "Subject", err)
+	}
+	h.add("Subject", subject)
+
+	h.add("Date", time.Now().Format(time.RFC1123Z))
+	h.add("Message-ID", formatMessageID(messageID))
+
+	// 会话头
+	if m.InReplyTo != "" {
+		h.add("In-Reply-To", formatMessageID(m.InReplyTo))
+	}
+	if len(m.References) > 0 {
+		h.add("References", formatMessageIDs(m.References))
+	}
+
+	// 退订
+	if u := m.Unsubscribe; u != nil {
+		h.add("List-Unsubscribe", u.header())
+		if u.OneClick && u.URL != "" {
+			h.add("List-Unsubscribe-Post", "List-Unsubscribe=One-Click")
+		}
+	}
+
+	// 优先级
+	m.Priority.addTo(h)
+
+	h.add("MIME-Version", "1.0")
+
+	// 自定义邮件头（按名称排序以保证输出稳定）
+	names := make([]string, 0, len(m.Headers))
+	for name := range m.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := checkHeaderName(name); err != nil {
+			return nil, err
+		}
+		if strings.EqualFold(name, "Message-ID") {
+			// 已经通过 messageID 写入
+			continue
+		}
+		if isReservedHeader(name) {
+			return nil, &HeaderInjectionError{Field: name, Reason: "header is generated automatically and cannot be overridden"}
+		}
+
+		value := m.Headers[name]
+		if err := checkHeaderValue(name, value); err != nil {
+			return nil, err
+		}
+		encoded, err := cs.headerText(value)
+		if err != nil {
+			return nil, fmt.Errorf("header %q: %w", name, err)
+		}
+		h.set(name, encoded)
+	}
+
+	return h, nil
+}
+
+// nonEmptyAddress 将可选的单个地址转换为列表，未设置时返回 nil
+func nonEmptyAddress(addr mail.Address) []mail.Address {
+	if addr.Address == "" {
+		return nil
+	}
+	return []mail.Address{addr}
+}
+
+// messageContent 是可以写入 SMTP DATA 或 sendmail 标准输入的完整邮件内容
+type messageContent interface {
+	io.WriterTo
+
+	// Size 返回内容的总字节数
+	Size() (int64, error)
+
+	// knownSize 返回已经计算出的字节数，尚未计算时返回 0
+	knownSize() int64
+
+	// use8BitMIME 在 SMTP 服务器声明 8BITMIME 时调用，允许内容使用 8bit 传输编码
+	// 返回内容是否包含 8bit 数据（需要在 MAIL FROM 中声明 BODY=8BITMIME）
+	use8BitMIME() bool
+
+	// deliveryNotification 返回邮件请求的投递状态通知，未请求时返回 nil
+	deliveryNotification() *DeliveryNotification
+}
+
+// renderedMessage 是已经构建好邮件头和 MIME 结构、可以写出的邮件
+//
+// 附件内容不会被读入内存，而是在写出时才打开并以 base64 流式编码写入目标
+// （SMTP 的 DATA 或 sendmail 的标准输入），因此内存占用与附件大小无关。
+// 如果某个附件的来源只能读取一次（没有实现 io.Seeker 的 io.Reader），
+// 整封邮件会在渲染时写入内存，以便计算大小和向多批收件人重复发送
+type renderedMessage struct {
+	header *mailHeader
+	body   *mimePart
+
+	// buffered 来源无法重放时缓存的完整邮件内容
+	buffered *bytes.Buffer
+
+	// size 邮件的总字节数，0 表示尚未计算
+	size int64
+
+	// dsn 邮件请求的投递状态通知
+	dsn *DeliveryNotification
+}
+
+// renderMessage 根据邮件头和邮件内容构建可写出的邮件
+func renderMessage(header *mailHeader, m *Message) (*renderedMessage, error) {
+	attachments := messageAttachments(m)
+
+	body, err := buildBody(m, attachments)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &renderedMessage{header: header, body: body, dsn: m.DeliveryNotification}
+
+	for i := range attachments {
+		if !attachments[i].replayable() {
+			r.buffered = &bytes.Buffer{}
+			if err := r.write(r.buffered); err != nil {
+				return nil, err
+			}
+			r.size = int64(r.buffered.Len())
+			break
+		}
+	}
+
+	return r, nil
+}
+
+// knownSize 实现 messageContent 接口
+func (r *renderedMessage) knownSize() int64 {
+	return r.size
+}
+
+// use8BitMIME 实现 messageContent 接口
+//
+// 已经缓存或转换（签名、加密）的内容保持 7bit 安全的编码
+func (r *renderedMessage) use8BitMIME() bool {
+	if r.buffered != nil {
+		return false
+	}
+
+	if !r.body.use8bit() {
+		return false
+	}
+
+	// 编码改变后需要重新计算大小
+	r.size = 0
+	return true
+}
+
+// deliveryNotification 实现 messageContent 接口
+func (r *renderedMessage) deliveryNotification() *DeliveryNotification {
+	return r.dsn
+}
+
+// Size 返回邮件的总字节数
+//
+// 对于流式写出的邮件，第一次调用时会完整渲染一遍（只计数，不保存内容）
+func (r *renderedMessage) Size() (int64, error) {
+	if r.size > 0 {
+		return r.size, nil
+	}
+
+	var counter countingWriter
+	if err := r.write(&counter); err != nil {
+		return 0, err
+	}
+
+	r.size = counter.n
+	return r.size, nil
+}
+
+// WriteTo 将完整的邮件（邮件头 + MIME 正文）写入 w，可以多次调用
+func (r *renderedMessage) WriteTo(w io.Writer) (int64, error) {
+	if r.buffered != nil {
+		n, err := w.Write(r.buffered.Bytes())
+		return int64(n), err
+	}
+
+	counter := &countingWriter{w: w}
+	buffered := bufio.NewWriterSize(counter, 32*1024)
+	if err := r.write(buffered); err != nil {
+		return counter.n, err
+	}
+	if err := buffered.Flush(); err != nil {
+		return counter.n, err
+	}
+
+	if r.size == 0 {
+		r.size = counter.n
+	}
+
+	return counter.n, nil
+}
+
+// write 渲染邮件头和 MIME 正文
+func (r *renderedMessage) write(w io.Writer) error {
+	if err := r.header.writeTo(w); err != nil {
+		return err
+	}
+
+	return r.body.writeTo(w)
+}
+
+// countingWriter 统计写入的字节数，w 为 nil 时只计数
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+// Write 实现 io.Writer 接口
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.w == nil {
+		c.n += int64(len(p))
+		return len(p), nil
+	}
+
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// -------------------------------------------------------------------
+// MIME 结构
+// -------------------------------------------------------------------
+
+// mimePart 表示 MIME 结构中的一个实体：叶子实体通过 body 写入内容，
+// multipart 实体则包含若干子实体
+type mimePart struct {
+	header   mailHeader
+	body     func(w io.Writer) error
+	parts    []*mimePart
+	boundary string
+
+	// raw 已经渲染好的完整实体（实体头 + 空行 + 内容），设置时原样写出
+	raw []byte
+
+	// to8bit 将实体切换为 8bit 传输编码，内容不能使用 8bit 时为 nil
+	to8bit func()
+}
+
+// use8bit 将所有可以使用 8bit 的实体切换为 8bit 传输编码，返回是否有实体被切换
+func (p *mimePart) use8bit() bool {
+	switched := false
+
+	if p.to8bit != nil {
+		p.to8bit()
+		p.to8bit = nil
+		switched = true
+	}
+
+	for _, part := range p.parts {
+		if part.use8bit() {
+			switched = true
+		}
+	}
+
+	return switched
+}
+
+// newMultipart 创建一个 multipart/<subtype> 实体
+func newMultipart(subtype string, parts ...*mimePart) *mimePart {
+	boundary := multipart.NewWriter(io.Discard).Boundary()
+
+	p := &mimePart{parts: parts, boundary: boundary}
+	p.header.add("Content-Type", mime.FormatMediaType("multipart/"+subtype, map[string]string{"boundary": boundary}))
+
+	return p
+}
+
+// writeTo 写入实体的头部、空行和内容
+func (p *mimePart) writeTo(w io.Writer) error {
+	if p.raw != nil {
+		_, err := w.Write(p.raw)
+		return err
+	}
+
+	if err := p.header.writeTo(w); err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(w, "\r\n"); err != nil {
+		return err
+	}
+
+	if p.parts == nil {
+		if p.body == nil {
+			return nil
+		}
+		return p.body(w)
+	}
+
+	for i, part := range p.parts {
+		delimiter := "\r\n--" + p.boundary + "\r\n"
+		if i == 0 {
+			delimiter = "--" + p.boundary + "\r\n"
+		}
+		if _, err := io.WriteString(w, delimiter); err != nil {
+			return err
+		}
+		if err := part.writeTo(w); err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(w, "\r\n--"+p.boundary+"--\r\n")
+	return err
+}
+
+// buildBody 根据邮件内容构建 MIME 结构
+//
+// 结构如下（省略不存在的部分）：
+//
+//	multipart/mixed
+//	├── multipart/alternative
+//	│   ├── text/plain
+//	│   ├── multipart/related
+//	│   │   ├── text/html
+//	│   │   └── 内联附件...
+//	│   └── text/calendar（会议邀请）
+//	└── 普通附件...
+func buildBody(m *Message, files []Attachment) (*mimePart, error) {
+	text := m.Text
+	if text == "" && m.HTML != "" {
+		// 尝试从 HTML 自动生成纯文本版本
+		if plain, err := html2Text(m.HTML); err == nil {
+			text = plain
+		}
+	}
+
+	var inline, attachments []*mimePart
+	for i := range files {
+		a := &files[i]
+		part, err := attachmentPart(a)
+		if err != nil {
+			return nil, err
+		}
+		if a.inline() {
+			inline = append(inline, part)
+		} else {
+			attachments = append(attachments, part)
+		}
+	}
+
+	var alternatives []*mimePart
+	if text != "" || m.HTML == "" {
+		part, err := textPart("text/plain", text, charsetOf(m.TextCharset, m.Charset))
+		if err != nil {
+			return nil, err
+		}
+		alternatives = append(alternatives, part)
+	}
+	if m.HTML != "" {
+		htmlPart, err := textPart("text/html", m.HTML, charsetOf(m.HTMLCharset, m.Charset))
+		if err != nil {
+			return nil, err
+		}
+		if len(inline) > 0 {
+			htmlPart = newMultipart("related", append([]*mimePart{htmlPart}, inline...)...)
+			inline = nil
+		}
+		alternatives = append(alternatives, htmlPart)
+	}
+
+	if m.Calendar != nil {
+		alternatives = append(alternatives, calendarPart(m.Calendar, m.Subject))
+	}
+
+	body := alternatives[0]
+	if len(alternatives) > 1 {
+		body = newMultipart("alternative", alternatives...)
+	}
+
+	// 没有 HTML 可供引用的内联附件与普通附件一起放入 multipart/mixed
+	attachments = append(inline, attachments...)
+	if len(attachments) > 0 {
+		body = newMultipart("mixed", append([]*mimePart{body}, attachments...)...)
+	}
+
+	return body, nil
+}
+
+// textPart 创建一个文本实体，内容从 UTF-8 转码为 charsetName（为空时使用 UTF-8），
+// 传输编码根据转码后的内容自动选择
+func textPart(mediaType, content, charsetName string) (*mimePart, error) {
+	cs, err := lookupCharset(charsetName)
+	if err != nil {
+		return nil, err
+	}
+
+	// 先统一换行再转码，转码后的内容中 CR 和 LF 仍然只表示换行
+	encoded, err := cs.encode(normalizeNewlines(content))
+	if err != nil {
+		return nil, fmt.Errorf("%s body: %w", mediaType, err)
+	}
+
+	return encodedTextPart(mime.FormatMediaType(mediaType, map[string]string{"charset": cs.name}), encoded), nil
+}
+
+// charsetOf 返回第一个非空的字符集名称
+func charsetOf(names ...string) string {
+	for _, name := range names {
+		if name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// attachmentPart 创建一个使用 base64 编码的附件实体
+//
+// 内联附件带有 Content-ID，可以在 HTML 中通过 "cid:<Content-ID>" 引用；
+// 附件内容在每次写出时才打开，流式编码后立即关闭
+func attachmentPart(a *Attachment) (*mimePart, error) {
+	if err := a.prepare(); err != nil {
+		return nil, err
+	}
+
+	contentType, err := a.resolveContentType()
+	if err != nil {
+		return nil, err
+	}
+
+	name := a.name()
+	disposition := DispositionAttachment
+	if a.inline() {
+		disposition = DispositionInline
+	}
+
+	dispositionParams := map[string]string{"filename": name}
+	if a.Size > 0 {
+		dispositionParams["size"] = strconv.FormatInt(a.Size, 10)
+	}
+
+	open := a.open
+	p := &mimePart{
+		body: func(w io.Writer) (err error) {
+			r, closeFn, err := open()
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if cerr := closeFn(); err == nil {
+					err = cerr
+				}
+			}()
+
+			encoder := base64.NewEncoder(base64.StdEncoding, &lineWrapper{w: w, max: 76})
+			if _, err := io.Copy(encoder, r); err != nil {
+				return err
+			}
+			return encoder.Close()
+		},
+	}
+
+	p.header.add("Content-Type", formatContentType(contentType, map[string]string{"name": name}))
+	p.header.add("Content-Disposition", mime.FormatMediaType(disposition, dispositionParams))
+	p.header.add("Content-Transfer-Encoding", "base64")
+	if a.inline() {
+		p.header.add("Content-ID", formatMessageID(a.contentID()))
+	}
+
+	return p, nil
+}
+
+// formatContentType 合并 MIME 类型自带的参数与额外参数并格式化
+//
+// 无法解析的类型回退为 application/octet-stream
+func formatContentType(contentType string, extra map[string]string) string {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType, params = "application/octet-stream", map[string]string{}
+	}
+
+	for k, v := range extra {
+		params[k] = v
+	}
+
+	if formatted := mime.FormatMediaType(mediaType, params); formatted != "" {
+		return formatted
+	}
+
+	return "application/octet-stream"
+}
+
+// crlf 是换行符，预先分配以避免在逐行写入时产生大量小对象
+var crlf = []byte("\r\n")
+
+// lineWrapper 每写入 max 个字节插入一个 CRLF，用于 base64 内容的换行
+type lineWrapper struct {
+	w   io.Writer
+	max int
+	n   int
+}
+
+// Write 实现 io.Writer 接口
+func (l *lineWrapper) Write(p []byte) (int, error) {
+	written := 0
+
+	for len(p) > 0 {
+		if l.n == l.max {
+			if _, err := l.w.Write(crlf); err != nil {
+				return written, err
+			}
+			l.n = 0
+		}
+
+		chunk := p
+		if room := l.max - l.n; len(chunk) > room {
+			chunk = chunk[:room]
+		}
+
+		n, err := l.w.Write(chunk)
+		written += n
+		l.n += n
+		if err != nil {
+			return written, err
+		}
+
+		p = p[len(chunk):]
+	}
+
+	return written, nil
+}
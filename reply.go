@@ -0,0 +1,110 @@
+package gomailer
+
+import (
+	"html"
+	"net/mail"
+	"strings"
+)
+
+// NewReply 基于一封已有的邮件创建回复邮件
+//
+// 生成的回复邮件：
+//   - 收件人为原邮件的 ReplyTo（未设置时为原邮件的 From）
+//   - replyAll 为 true 时，原邮件的其它 To/Cc 收件人（排除 from 自己）会被加入 Cc
+//   - 主题添加 "Re: " 前缀（已有前缀时不重复添加）
+//   - In-Reply-To 和 References 指向原邮件（MessageID 字段或 Headers 中的 Message-ID），保持会话线索；
+//     原邮件没有 Message-ID 时（例如尚未发送、由 Send 自动生成 Message-ID 的邮件）无法引用，
+//     按 RFC 5322 3.6.4 不设置这两个字段，回复会成为新的会话。需要保持线索时先为原邮件设置 MessageID
+//   - Text 和 HTML 正文为引用的原邮件内容，调用方可以在其前面追加回复内容
+//
+// 参数:
+//   - original: 被回复的邮件
+//   - from: 回复者地址
+//   - replyAll: 是否回复全部
+//
+// 返回:
+//   - *Message: 新的回复邮件
+func NewReply(original *Message, from mail.Address, replyAll bool) *Message {
+	reply := &Message{From: from}
+	if original == nil {
+		return reply
+	}
+
+	// 收件人
+	if len(original.ReplyTo) > 0 {
+		reply.To = append(reply.To, original.ReplyTo...)
+	} else {
+		reply.To = append(reply.To, original.From)
+	}
+
+	if replyAll {
+		seen := map[string]bool{strings.ToLower(from.Address): true}
+		for _, addr := range reply.To {
+			seen[strings.ToLower(addr.Address)] = true
+		}
+
+		for _, list := range [][]mail.Address{original.To, original.Cc} {
+			for _, addr := range list {
+				key := strings.ToLower(addr.Address)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				reply.Cc = append(reply.Cc, addr)
+			}
+		}
+	}
+
+	// 主题
+	reply.Subject = original.Subject
+	if !strings.HasPrefix(strings.ToLower(reply.Subject), "re:") {
+		reply.Subject = "Re: " + reply.Subject
+	}
+
+	// 会话头：References 为原邮件的 References（没有时使用其 In-Reply-To）加上原邮件的 Message-ID
+	if id := messageIDOf(original); id != "" {
+		reply.InReplyTo = id
+
+		if len(original.References) > 0 {
+			reply.References = append(reply.References, original.References...)
+		} else if original.InReplyTo != "" {
+			reply.References = append(reply.References, original.InReplyTo)
+		}
+		reply.References = append(reply.References, id)
+	}
+
+	// 引用原邮件正文
+	attribution := original.From.Address + " wrote:"
+	if original.From.Name != "" {
+		attribution = original.From.Name + " <" + original.From.Address + "> wrote:"
+	}
+
+	text := original.Text
+	if text == "" && original.HTML != "" {
+		text, _ = html2Text(original.HTML)
+	}
+	reply.Text = "\r\n\r\n" + attribution + "\r\n" + quoteText(text)
+
+	if original.HTML != "" {
+		reply.HTML = "<br><br><div>" + html.EscapeString(attribution) + "</div>" +
+			`<blockquote type="cite" style="margin:0 0 0 .8ex;border-left:1px solid #ccc;padding-left:1ex">` +
+			original.HTML +
+			"</blockquote>"
+	}
+
+	return reply
+}
+
+// quoteText 为纯文本的每一行添加 "> " 引用前缀
+func quoteText(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, ">") {
+			lines[i] = ">" + line
+		} else {
+			lines[i] = "> " + line
+		}
+	}
+
+	return strings.Join(lines, "\r\n")
+}
@@ -0,0 +1,143 @@
+package gomailer
+
+import (
+	"net/mail"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewReplySubject(t *testing.T) {
+	tests := []struct {
+		subject string
+		want    string
+	}{
+		{"Hello", "Re: Hello"},
+		{"Re: Hello", "Re: Hello"},
+		{"RE: Hello", "RE: Hello"},
+		{"re:Hello", "re:Hello"},
+		{"", "Re: "},
+		{"Reminder", "Re: Reminder"},
+	}
+
+	for _, tt := range tests {
+		reply := NewReply(&Message{Subject: tt.subject}, mail.Address{Address: "me@example.com"}, false)
+		if reply.Subject != tt.want {
+			t.Errorf("subject %q: got %q, want %q", tt.subject, reply.Subject, tt.want)
+		}
+	}
+}
+
+func TestNewReplyReferences(t *testing.T) {
+	tests := []struct {
+		name           string
+		original       *Message
+		wantInReplyTo  string
+		wantReferences []string
+	}{
+		{
+			name:           "first reply",
+			original:       &Message{MessageID: "a@example.com"},
+			wantInReplyTo:  "a@example.com",
+			wantReferences: []string{"a@example.com"},
+		},
+		{
+			name:           "chains references",
+			original:       &Message{MessageID: "c@example.com", InReplyTo: "b@example.com", References: []string{"a@example.com", "b@example.com"}},
+			wantInReplyTo:  "c@example.com",
+			wantReferences: []string{"a@example.com", "b@example.com", "c@example.com"},
+		},
+		{
+			name:           "falls back to in-reply-to",
+			original:       &Message{MessageID: "b@example.com", InReplyTo: "a@example.com"},
+			wantInReplyTo:  "b@example.com",
+			wantReferences: []string{"a@example.com", "b@example.com"},
+		},
+		{
+			name:           "message id from headers",
+			original:       &Message{Headers: map[string]string{"Message-ID": "<h@example.com>"}},
+			wantInReplyTo:  "h@example.com",
+			wantReferences: []string{"h@example.com"},
+		},
+		{
+			name:     "no message id",
+			original: &Message{InReplyTo: "a@example.com", References: []string{"a@example.com"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reply := NewReply(tt.original, mail.Address{Address: "me@example.com"}, false)
+			if reply.InReplyTo != tt.wantInReplyTo {
+				t.Errorf("InReplyTo = %q, want %q", reply.InReplyTo, tt.wantInReplyTo)
+			}
+			if !reflect.DeepEqual(reply.References, tt.wantReferences) {
+				t.Errorf("References = %q, want %q", reply.References, tt.wantReferences)
+			}
+		})
+	}
+
+	// 回复不会修改原邮件的 References
+	original := &Message{MessageID: "c@example.com", References: make([]string, 2, 10)}
+	original.References[0], original.References[1] = "a@example.com", "b@example.com"
+	NewReply(original, mail.Address{Address: "me@example.com"}, false)
+	if got := original.References[:3]; got[2] != "" {
+		t.Errorf("original References backing array was modified: %q", got)
+	}
+}
+
+func TestNewReplyRecipients(t *testing.T) {
+	me := mail.Address{Name: "Me", Address: "me@example.com"}
+	original := &Message{
+		From: mail.Address{Name: "Alice", Address: "alice@example.com"},
+		To: []mail.Address{
+			{Address: "ME@example.com"},
+			{Address: "bob@example.com"},
+			{Address: "Alice@Example.com"},
+		},
+		Cc: []mail.Address{
+			{Address: "carol@example.com"},
+			{Name: "Bob again", Address: "BOB@example.com"},
+		},
+	}
+
+	reply := NewReply(original, me, false)
+	if !reflect.DeepEqual(reply.To, []mail.Address{original.From}) || len(reply.Cc) != 0 {
+		t.Errorf("reply: To = %v, Cc = %v", reply.To, reply.Cc)
+	}
+
+	all := NewReply(original, me, true)
+	if !reflect.DeepEqual(all.To, []mail.Address{original.From}) {
+		t.Errorf("reply all: To = %v", all.To)
+	}
+	var cc []string
+	for _, addr := range all.Cc {
+		cc = append(cc, addr.Address)
+	}
+	if want := []string{"bob@example.com", "carol@example.com"}; !reflect.DeepEqual(cc, want) {
+		t.Errorf("reply all: Cc = %q, want %q", cc, want)
+	}
+
+	// Reply-To 优先于 From
+	original.ReplyTo = []mail.Address{{Address: "list@example.com"}}
+	if reply := NewReply(original, me, false); reply.To[0].Address != "list@example.com" {
+		t.Errorf("To = %v, want Reply-To", reply.To)
+	}
+}
+
+func TestNewReplyQuotesBody(t *testing.T) {
+	original := &Message{
+		From: mail.Address{Name: "Alice", Address: "alice@example.com"},
+		Text: "line one\n> earlier",
+		HTML: "<p>line one</p>",
+	}
+
+	reply := NewReply(original, mail.Address{Address: "me@example.com"}, false)
+	if !strings.Contains(reply.Text, "Alice <alice@example.com> wrote:\r\n> line one\r\n>> earlier") {
+		t.Errorf("Text = %q", reply.Text)
+	}
+	if !strings.Contains(reply.HTML, "<blockquote") || !strings.Contains(reply.HTML, original.HTML) ||
+		!strings.Contains(reply.HTML, "Alice &lt;alice@example.com&gt; wrote:") {
+		t.Errorf("HTML = %q", reply.HTML)
+	}
+}
@@ -1,12 +1,9 @@
 package gomailer
 
 import (
-    "bytes"
     "errors"
-    "mime"
-    "net/http"
+    "fmt"
     "os/exec"
-    "strings"
 )
 
 // 确保 Sendmail 实现了 Mailer 接口
@@ -22,6 +19,22 @@ var _ Mailer = (*Sendmail)(nil)
 type Sendmail struct {
 	// onSend 发送钩子，允许在发送前后执行自定义逻辑
 	onSend *Hook[*SendEvent]
+
+	// onSendRaw 原始邮件（SendRaw）的发送钩子
+	onSendRaw *Hook[*SendRawEvent]
+
+	// MaxMessageSize 允许发送的最大邮件大小（编码后的字节数）
+	// 超出时不会调用 sendmail 命令，直接返回 *MessageSizeError
+	// 如果未明确设置（0），不做限制
+	MaxMessageSize int64
+
+	// MessageIDDomain 自动生成 Message-ID 时使用的域名
+	// 如果未明确设置，使用发件人地址的域名
+	MessageIDDomain string
+
+	// Transformers 在发送前依次对渲染后的正文进行转换（如 S/MIME 签名和加密）
+	// 不会作用于 SendRaw 发送的原始邮件
+	Transformers []Transformer
 }
 
 // OnSend 实现 SendInterceptor 接口
@@ -48,47 +61,120 @@ func (c *Sendmail) OnSend() *Hook[*SendEvent] {
 // 返回:
 //   - error: 发送失败时返回错误，成功返回 nil
 //
-// 注意事项:
-//   - 仅支持发送到 To 字段的收件人（不支持 Cc 和 Bcc）
-//   - 不支持附件
-//   - 优先发送 HTML 内容，如果没有 HTML 则发送纯文本
+// 邮件内容与 SMTPClient 使用相同的方式渲染，支持 Cc、Bcc 和附件
 func (c *Sendmail) Send(m *Message) error {
+	// 邮件为 nil 时直接返回校验错误，避免生成 Message-ID 时解引用
+	if m == nil {
+		return m.Validate()
+	}
+
+	id, err := ensureMessageID(m, c.MessageIDDomain)
+	if err != nil {
+		return err
+	}
+
 	if c.onSend != nil {
-		return c.onSend.Trigger(&SendEvent{Message: m}, func(e *SendEvent) error {
-			return c.send(e.Message)
+		return c.onSend.Trigger(&SendEvent{Message: m, MessageID: id}, func(e *SendEvent) error {
+			// 钩子可能替换了邮件，邮件自身的 Message-ID 优先
+			if own := messageIDOf(e.Message); own != "" {
+				e.MessageID = own
+			}
+			return c.send(e.Message, e.MessageID)
 		})
 	}
 
-	return c.send(m)
+	return c.send(m, id)
 }
 
 // send 内部发送方法，执行实际的 sendmail 调用
-func (c *Sendmail) send(m *Message) error {
-    // 基础输入校验
-    if m == nil {
-        return errors.New("message is nil")
-    }
-    if m.From.Address == "" {
-        return errors.New("from address is required")
-    }
-    if len(m.To) == 0 {
-        return errors.New("at least one recipient in To is required")
-    }
-
-    // 提取收件人邮箱地址（不包含姓名）
-    toAddresses := addressesToStrings(m.To, false)
-
-	// 构建邮件头部
-    headers := make(http.Header)
-    headers.Set("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
-    headers.Set("From", m.From.String())
-    // 根据正文选择合适的 Content-Type
-    if m.HTML != "" {
-        headers.Set("Content-Type", "text/html; charset=UTF-8")
-    } else {
-        headers.Set("Content-Type", "text/plain; charset=UTF-8")
-    }
-    headers.Set("To", strings.Join(toAddresses, ","))
+func (c *Sendmail) send(m *Message, messageID string) error {
+	// 校验邮件消息
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	// 提取收件人邮箱地址（不包含姓名），Bcc 只作为参数传递，不会写入邮件头
+	recipients := make([]string, 0, len(m.To)+len(m.Cc)+len(m.Bcc))
+	recipients = append(recipients, addressesToStrings(m.To, false)...)
+	recipients = append(recipients, addressesToStrings(m.Cc, false)...)
+	recipients = append(recipients, addressesToStrings(m.Bcc, false)...)
+
+	// 构建邮件头部（与 SMTPClient 共用同一套编码和头部注入检查）
+	header, err := buildHeader(m, messageID)
+	if err != nil {
+		return err
+	}
+
+	// 构建邮件内容（附件在写入 sendmail 标准输入时才流式读取）
+	body, err := renderMessage(header, m)
+	if err != nil {
+		return err
+	}
+
+	env := &Envelope{From: m.From.Address, Recipients: recipients}
+	if err := body.transform(c.Transformers, m, env); err != nil {
+		return err
+	}
+
+	var options []string
+	if m.DeliveryNotification != nil {
+		options = m.DeliveryNotification.sendmailArgs()
+	}
+
+	args, err := sendmailArgs(env.From, env.Recipients, options...)
+	if err != nil {
+		return err
+	}
+
+	return c.pipe(args, body)
+}
+
+// sendmailArgs 生成 sendmail 的命令行参数，Send 和 SendRaw 共用
+//
+// 使用 -i 使单独一行的 "." 不会被当作输入结束（quoted-printable 和 7bit 正文中可能出现），
+// 信封发件人通过 -f 传递，options 中的其它选项（如 DSN 的 -N）放在收件人之前。
+// 以 "-" 开头的地址会被 sendmail 当作命令行选项，因此直接拒绝
+func sendmailArgs(from string, recipients []string, options ...string) ([]string, error) {
+	var errs ValidationErrors
+
+	if from != "" && !validEnvelopeAddress(from) {
+		errs.add("from", "invalid envelope address %q", from)
+	}
+
+	for i, rcpt := range recipients {
+		if !validEnvelopeAddress(rcpt) {
+			errs.add(fmt.Sprintf("recipients[%d]", i), "invalid envelope address %q", rcpt)
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	args := []string{"-i"}
+	if from != "" {
+		args = append(args, "-f", from)
+	}
+	args = append(args, options...)
+
+	return append(args, recipients...), nil
+}
+
+// pipe 调用 sendmail 命令并将内容写入其标准输入，Send 和 SendRaw 共用
+//
+// 参数:
+//   - args: sendmail 的命令行参数（选项和收件人）
+//   - body: 完整的邮件内容
+func (c *Sendmail) pipe(args []string, body messageContent) error {
+	if c.MaxMessageSize > 0 {
+		size, err := body.Size()
+		if err != nil {
+			return err
+		}
+		if size > c.MaxMessageSize {
+			return &MessageSizeError{Size: size, Limit: c.MaxMessageSize}
+		}
+	}
 
 	// 查找 sendmail 可执行文件路径
 	cmdPath, err := findSendmailPath()
@@ -96,41 +182,29 @@ func (c *Sendmail) send(m *Message) error {
 		return err
 	}
 
-	// 构建邮件内容
-	var buffer bytes.Buffer
+	sendmail := exec.Command(cmdPath, args...)
+	stdin, err := sendmail.StdinPipe()
+	if err != nil {
+		return err
+	}
+
+	if err := sendmail.Start(); err != nil {
+		return err
+	}
 
-	// 写入邮件头部
-	if err := headers.Write(&buffer); err != nil {
+	if _, err := body.WriteTo(stdin); err != nil {
+		// 先结束进程再关闭输入，避免 sendmail 把不完整的邮件当作完整内容投递
+		_ = sendmail.Process.Kill()
+		_ = sendmail.Wait()
 		return err
 	}
 
-	// 添加空行分隔头部和正文
-	if _, err := buffer.Write([]byte("\r\n")); err != nil {
+	if err := stdin.Close(); err != nil {
+		_ = sendmail.Wait()
 		return err
 	}
 
-    // 写入邮件正文（优先使用 HTML），确保至少有一个正文
-    if m.HTML != "" {
-        if _, err := buffer.Write([]byte(m.HTML)); err != nil {
-            return err
-        }
-    } else if m.Text != "" {
-        if _, err := buffer.Write([]byte(m.Text)); err != nil {
-            return err
-        }
-    } else {
-        // 回退一个最小正文，避免空 body 导致部分 MTA 拒收
-        if _, err := buffer.Write([]byte("(empty body)")); err != nil {
-            return err
-        }
-    }
-
-    // 执行 sendmail 命令：以独立参数传递收件人
-    // 参考：大多数 sendmail 兼容实现期望每个收件人为单独参数
-    sendmail := exec.Command(cmdPath, toAddresses...)
-    sendmail.Stdin = &buffer
-
-    return sendmail.Run()
+	return sendmail.Wait()
 }
 
 // findSendmailPath 查找系统中 sendmail 可执行文件的路径
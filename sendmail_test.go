@@ -0,0 +1,129 @@
+package gomailer
+
+import (
+	"errors"
+	"net/mail"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestSendmailArgs(t *testing.T) {
+	tests := []struct {
+		name       string
+		from       string
+		recipients []string
+		want       []string
+		wantErr    bool
+	}{
+		{"with sender", "sender@example.com", []string{"a@example.com", "b@example.com"},
+			[]string{"-i", "-f", "sender@example.com", "a@example.com", "b@example.com"}, false},
+		{"null sender", "", []string{"a@example.com"}, []string{"-i", "a@example.com"}, false},
+		{"option injection in recipient", "sender@example.com", []string{"-X/tmp/log@example.com"}, nil, true},
+		{"option injection in sender", "-oQ/tmp@example.com", []string{"a@example.com"}, nil, true},
+		{"invalid recipient", "sender@example.com", []string{"not an address"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sendmailArgs(tt.from, tt.recipients)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidMessage) {
+					t.Fatalf("err = %v, want ErrInvalidMessage", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("args = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// installFakeSendmail 在 PATH 中放置一个记录参数和标准输入的 sendmail 脚本
+func installFakeSendmail(t *testing.T) (argsFile, bodyFile string) {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("sendmail is not available on windows")
+	}
+	for _, path := range []string{"/usr/sbin/sendmail", "/usr/bin/sendmail"} {
+		if _, err := os.Stat(path); err == nil {
+			t.Skipf("%s takes precedence over PATH", path)
+		}
+	}
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+
+	dir := t.TempDir()
+	argsFile = filepath.Join(dir, "args")
+	bodyFile = filepath.Join(dir, "body")
+
+	script := "#!/bin/sh\nprintf '%s\\n' \"$@\" > '" + argsFile + "'\ncat > '" + bodyFile + "'\n"
+	if err := os.WriteFile(filepath.Join(dir, "sendmail"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	return argsFile, bodyFile
+}
+
+func TestSendmailSend(t *testing.T) {
+	argsFile, bodyFile := installFakeSendmail(t)
+
+	m := &Message{
+		From: mail.Address{Address: "sender@example.com"},
+		To:   []mail.Address{{Address: "to@example.com"}},
+		Bcc:  []mail.Address{{Address: "bcc@example.com"}},
+		Text: "first line\n.\nlast line",
+	}
+
+	if err := (&Sendmail{}).Send(m); err != nil {
+		t.Fatal(err)
+	}
+
+	args, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"-i", "-f", "sender@example.com", "to@example.com", "bcc@example.com"}
+	if got := strings.Fields(string(args)); !reflect.DeepEqual(got, want) {
+		t.Errorf("args = %q, want %q", got, want)
+	}
+
+	body, err := os.ReadFile(bodyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), "\r\n.\r\nlast line") {
+		t.Error("a line containing only \".\" must be passed through unchanged")
+	}
+	if strings.Contains(string(body), "bcc@example.com") {
+		t.Error("Bcc must not appear in the message")
+	}
+}
+
+func TestSendmailRejectsOptionLikeRecipients(t *testing.T) {
+	argsFile, _ := installFakeSendmail(t)
+
+	m := &Message{
+		From: mail.Address{Address: "sender@example.com"},
+		To:   []mail.Address{{Address: "-X/tmp/log@example.com"}},
+		Text: "hello",
+	}
+
+	if err := (&Sendmail{}).Send(m); !errors.Is(err, ErrInvalidMessage) {
+		t.Fatalf("err = %v, want ErrInvalidMessage", err)
+	}
+	if _, err := os.Stat(argsFile); err == nil {
+		t.Error("sendmail must not be invoked")
+	}
+}
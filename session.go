@@ -0,0 +1,205 @@
+package gomailer
+
+import (
+	"context"
+	"net/smtp"
+	"sync"
+)
+
+// SessionMailer 是一个可选接口，由可以在多封邮件之间复用连接的传输实现
+//
+// BulkSender 会为每个 worker 创建一个会话，worker 发送的所有邮件复用同一个连接，
+// 避免每封邮件都重新连接、STARTTLS 和认证
+type SessionMailer interface {
+	Mailer
+
+	// NewSession 创建一个会话，使用完毕后需要调用 Close
+	NewSession() MailerSession
+}
+
+// MailerSession 是通过 SessionMailer 创建的会话
+//
+// 会话中的多次 Send 复用同一个连接，会话内部串行发送，
+// 需要并发发送时应为每个 goroutine 创建单独的会话
+type MailerSession interface {
+	Mailer
+
+	// Close 结束会话并关闭连接
+	Close() error
+}
+
+// newSession 为 mailer 创建会话，不支持会话的 Mailer 直接使用 Send
+func newSession(mailer Mailer) MailerSession {
+	if sm, ok := mailer.(SessionMailer); ok {
+		return sm.NewSession()
+	}
+	return &mailerSession{Mailer: mailer}
+}
+
+// mailerSession 是不支持会话的 Mailer 的会话实现，Close 不做任何事
+type mailerSession struct {
+	Mailer
+}
+
+// SendContext 实现 ContextMailer 接口
+func (s *mailerSession) SendContext(ctx context.Context, m *Message) error {
+	return sendWithContext(ctx, s.Mailer, m)
+}
+
+// Close 实现 MailerSession 接口
+func (s *mailerSession) Close() error {
+	return nil
+}
+
+// wrapperSession 是 RateLimitedMailer、CircuitBreakerMailer 等包装器的会话实现，
+// 通过包装器的逻辑将邮件发送到被包装 Mailer 的会话
+type wrapperSession struct {
+	inner MailerSession
+	send  func(ctx context.Context, target Mailer, m *Message) error
+}
+
+// Send 实现 Mailer 接口
+func (s *wrapperSession) Send(m *Message) error {
+	return s.SendContext(context.Background(), m)
+}
+
+// SendContext 实现 ContextMailer 接口
+func (s *wrapperSession) SendContext(ctx context.Context, m *Message) error {
+	return s.send(ctx, s.inner, m)
+}
+
+// Close 实现 MailerSession 接口
+func (s *wrapperSession) Close() error {
+	return s.inner.Close()
+}
+
+// -------------------------------------------------------------------
+// SMTP 会话
+// -------------------------------------------------------------------
+
+// 确保 SMTPClient 和包装器实现了 SessionMailer 接口
+var (
+	_ SessionMailer = (*SMTPClient)(nil)
+	_ SessionMailer = (*RateLimitedMailer)(nil)
+	_ SessionMailer = (*CircuitBreakerMailer)(nil)
+	_ MailerSession = (*SMTPSession)(nil)
+)
+
+// SMTPSession 是一个可以连续发送多封邮件的 SMTP 连接
+//
+// 连接在第一次发送时建立；之后每封邮件前先发送 RSET 确认连接可用，
+// 服务器已经关闭空闲连接时自动重新连接。发送钩子、MaxMessageSize、
+// MaxRecipientsPerMessage 和 Transformers 与 SMTPClient.Send 相同
+type SMTPSession struct {
+	client *SMTPClient
+
+	mu   sync.Mutex
+	conn *smtp.Client
+}
+
+// NewSession 实现 SessionMailer 接口
+// 创建一个复用连接的 SMTP 会话，返回值为 *SMTPSession
+//
+// 示例:
+//
+//	session := client.NewSession()
+//	defer session.Close()
+//	for _, m := range messages {
+//		if err := session.Send(m); err != nil {
+//			log.Println(err)
+//		}
+//	}
+func (c *SMTPClient) NewSession() MailerSession {
+	return &SMTPSession{client: c}
+}
+
+// Send 实现 Mailer 接口
+// 通过会话的连接发送邮件
+//
+// 参数:
+//   - m: 要发送的邮件消息
+//
+// 返回:
+//   - error: 发送失败时返回错误，部分收件人失败时返回 *DeliveryError
+func (s *SMTPSession) Send(m *Message) error {
+	// 邮件为 nil 时直接返回校验错误，避免生成 Message-ID 时解引用
+	if m == nil {
+		return m.Validate()
+	}
+
+	id, err := ensureMessageID(m, s.client.MessageIDDomain)
+	if err != nil {
+		return err
+	}
+
+	return s.client.sendWith(m, id, s.deliver)
+}
+
+// deliver 在会话的连接上投递邮件，连接不可再用时关闭，下一封邮件重新连接
+func (s *SMTPSession) deliver(from string, recipients []string, body messageContent) error {
+	if err := s.client.checkSize(body); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 复用的连接可能已经被服务器关闭
+	if s.conn != nil {
+		if err := s.conn.Reset(); err != nil {
+			s.conn.Close()
+			s.conn = nil
+		}
+	}
+
+	if s.conn == nil {
+		conn, err := s.client.connect()
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+
+	usable, err := s.client.deliverOn(s.conn, from, recipients, body)
+	if !usable {
+		s.conn.Close()
+		s.conn = nil
+	}
+
+	return err
+}
+
+// Close 实现 MailerSession 接口
+// 发送 QUIT 并关闭连接，会话之后仍然可以使用（会重新连接）
+func (s *SMTPSession) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+
+	err := s.conn.Quit()
+	if err != nil {
+		s.conn.Close()
+	}
+	s.conn = nil
+
+	return err
+}
+
+// -------------------------------------------------------------------
+// 包装器的会话
+// -------------------------------------------------------------------
+
+// NewSession 实现 SessionMailer 接口
+// 被包装的 Mailer 支持会话时，返回的会话在限流后通过其会话发送
+func (r *RateLimitedMailer) NewSession() MailerSession {
+	return &wrapperSession{inner: newSession(r.Mailer), send: r.sendVia}
+}
+
+// NewSession 实现 SessionMailer 接口
+// 被包装的 Mailer 支持会话时，返回的会话经过熔断器后通过其会话发送，与 Send 共用熔断器状态
+func (b *CircuitBreakerMailer) NewSession() MailerSession {
+	return &wrapperSession{inner: newSession(b.Mailer), send: b.sendVia}
+}
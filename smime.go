@@ -0,0 +1,313 @@
+package gomailer
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"mime"
+	"strings"
+
+	"github.com/smallstep/pkcs7"
+)
+
+// 确保 SMIME 实现了 Transformer 接口
+var _ Transformer = (*SMIME)(nil)
+
+// SMIME 使用 S/MIME（RFC 8551）对邮件签名和加密，作为 Transformer 配置在传输上
+//
+//   - 签名：生成 multipart/signed，签名为分离的 PKCS#7（application/pkcs7-signature），
+//     不支持 S/MIME 的客户端仍然可以正常阅读邮件
+//   - 加密：生成 application/pkcs7-mime; smime-type=enveloped-data，使用 AES-256-CBC
+//
+// 同时签名和加密时先签名再加密。签名摘要算法为 SHA-256
+//
+// 加密的邮件不能有 Bcc 收件人：每个收件人都能从 RecipientInfos 中看到其他收件人的证书，
+// 需要加密发送给 Bcc 收件人时，为每个人单独发送一封邮件
+//
+// 示例:
+//
+//	client := &SMTPClient{
+//		// ...
+//		Transformers: []Transformer{&SMIME{
+//			Certificate: cert,
+//			PrivateKey:  key,
+//			Encrypt:     true,
+//			RecipientCertificates: map[string]*x509.Certificate{
+//				"finance@example.com": financeCert,
+//			},
+//		}},
+//	}
+type SMIME struct {
+	// Certificate 签名证书，设置后对邮件签名（需要同时设置 PrivateKey）
+	Certificate *x509.Certificate
+
+	// PrivateKey 签名证书对应的私钥（*rsa.PrivateKey 或 *ecdsa.PrivateKey）
+	PrivateKey crypto.PrivateKey
+
+	// Intermediates 随签名一起发送的中间证书，便于收件人验证证书链
+	Intermediates []*x509.Certificate
+
+	// Encrypt 是否加密邮件，加密时每个信封收件人都必须在 RecipientCertificates 中有证书，
+	// 邮件有 Bcc 收件人时返回 ValidationErrors
+	Encrypt bool
+
+	// RecipientCertificates 收件人地址 -> 加密证书（RSA 公钥），地址不区分大小写
+	RecipientCertificates map[string]*x509.Certificate
+}
+
+// Transform 实现 Transformer 接口
+func (s *SMIME) Transform(m *Message, env *Envelope, entity []byte) ([]byte, error) {
+	sign := s.Certificate != nil || s.PrivateKey != nil
+	if !sign && !s.Encrypt {
+		return nil, errors.New("smime: certificate or encrypt is required")
+	}
+
+	var err error
+	if sign {
+		if entity, err = s.sign(entity); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.Encrypt {
+		if err := checkEncryptedBcc(m, env); err != nil {
+			return nil, err
+		}
+		if entity, err = s.encrypt(env.Recipients, entity); err != nil {
+			return nil, err
+		}
+	}
+
+	return entity, nil
+}
+
+// sign 生成 multipart/signed 实体，entity 原样作为第一部分
+func (s *SMIME) sign(entity []byte) ([]byte, error) {
+	if s.Certificate == nil || s.PrivateKey == nil {
+		return nil, errors.New("smime: signing requires both certificate and private key")
+	}
+
+	signed, err := pkcs7.NewSignedData(entity)
+	if err != nil {
+		return nil, fmt.Errorf("smime: %w", err)
+	}
+	signed.SetDigestAlgorithm(pkcs7.OIDDigestAlgorithmSHA256)
+
+	if err := signed.AddSignerChain(s.Certificate, s.PrivateKey, s.Intermediates, pkcs7.SignerInfoConfig{}); err != nil {
+		return nil, fmt.Errorf("smime: sign: %w", err)
+	}
+	signed.Detach()
+
+	signature, err := signed.Finish()
+	if err != nil {
+		return nil, fmt.Errorf("smime: sign: %w", err)
+	}
+
+	signaturePart := pkcs7Part("application/pkcs7-signature", nil, "smime.p7s", signature)
+
+	p := newMultipart("signed", &mimePart{raw: entity}, signaturePart)
+	p.header.set("Content-Type", mime.FormatMediaType("multipart/signed", map[string]string{
+		"protocol": "application/pkcs7-signature",
+		"micalg":   "sha-256",
+		"boundary": p.boundary,
+	}))
+
+	return renderEntity(p)
+}
+
+// encrypt 生成 application/pkcs7-mime 加密实体
+func (s *SMIME) encrypt(recipients []string, entity []byte) ([]byte, error) {
+	certs := make([]*x509.Certificate, 0, len(recipients))
+	for _, rcpt := range recipients {
+		cert := s.recipientCertificate(rcpt)
+		if cert == nil {
+			return nil, fmt.Errorf("smime: no certificate for recipient %q", rcpt)
+		}
+		certs = append(certs, cert)
+	}
+
+	enveloped, err := envelope(entity, certs)
+	if err != nil {
+		return nil, fmt.Errorf("smime: encrypt: %w", err)
+	}
+
+	p := pkcs7Part("application/pkcs7-mime", map[string]string{"smime-type": "enveloped-data"}, "smime.p7m", enveloped)
+	return renderEntity(p)
+}
+
+// recipientCertificate 查找收件人的加密证书（地址不区分大小写）
+func (s *SMIME) recipientCertificate(address string) *x509.Certificate {
+	if cert, ok := s.RecipientCertificates[address]; ok {
+		return cert
+	}
+
+	for k, cert := range s.RecipientCertificates {
+		if strings.EqualFold(k, address) {
+			return cert
+		}
+	}
+
+	return nil
+}
+
+// -------------------------------------------------------------------
+// CMS EnvelopedData（RFC 5652 第 6 节）
+// -------------------------------------------------------------------
+
+var (
+	oidData             = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidEnvelopedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 3}
+	oidRSAEncryption    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidAES256CBC        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	cmsContentInfoTag   = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true}
+	cmsEncryptedDataTag = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0}
+)
+
+type cmsContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue
+}
+
+type cmsEnvelopedData struct {
+	Version              int
+	RecipientInfos       []cmsRecipientInfo `asn1:"set"`
+	EncryptedContentInfo cmsEncryptedContentInfo
+}
+
+type cmsRecipientInfo struct {
+	Version                int
+	IssuerAndSerialNumber  cmsIssuerAndSerial
+	KeyEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedKey           []byte
+}
+
+type cmsIssuerAndSerial struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type cmsEncryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           asn1.RawValue
+}
+
+// envelope 使用 AES-256-CBC 加密 content，内容密钥用每个证书的 RSA 公钥（PKCS#1 v1.5）加密
+//
+// 算法在这里固定选择，不依赖 pkcs7 包的全局配置，可以与同一进程中的其它 pkcs7 使用者并发执行
+func envelope(content []byte, certs []*x509.Certificate) ([]byte, error) {
+	key := make([]byte, 32)
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// PKCS#7 填充
+	padding := aes.BlockSize - len(content)%aes.BlockSize
+	encrypted := make([]byte, len(content)+padding)
+	copy(encrypted, content)
+	for i := len(content); i < len(encrypted); i++ {
+		encrypted[i] = byte(padding)
+	}
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, encrypted)
+
+	ivParam, err := asn1.Marshal(iv)
+	if err != nil {
+		return nil, err
+	}
+
+	recipients := make([]cmsRecipientInfo, len(certs))
+	for i, cert := range certs {
+		pub, ok := cert.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("certificate %q does not have an RSA public key", cert.Subject.CommonName)
+		}
+
+		encryptedKey, err := rsa.EncryptPKCS1v15(rand.Reader, pub, key)
+		if err != nil {
+			return nil, err
+		}
+
+		recipients[i] = cmsRecipientInfo{
+			IssuerAndSerialNumber:  cmsIssuerAndSerial{Issuer: asn1.RawValue{FullBytes: cert.RawIssuer}, SerialNumber: cert.SerialNumber},
+			KeyEncryptionAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidRSAEncryption, Parameters: asn1.NullRawValue},
+			EncryptedKey:           encryptedKey,
+		}
+	}
+
+	encryptedContent := cmsEncryptedDataTag
+	encryptedContent.Bytes = encrypted
+
+	inner, err := asn1.Marshal(cmsEnvelopedData{
+		RecipientInfos: recipients,
+		EncryptedContentInfo: cmsEncryptedContentInfo{
+			ContentType:                oidData,
+			ContentEncryptionAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidAES256CBC, Parameters: asn1.RawValue{FullBytes: ivParam}},
+			EncryptedContent:           encryptedContent,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped := cmsContentInfoTag
+	wrapped.Bytes = inner
+
+	return asn1.Marshal(cmsContentInfo{ContentType: oidEnvelopedData, Content: wrapped})
+}
+
+// pkcs7Part 创建一个 base64 编码的 PKCS#7 实体
+func pkcs7Part(mediaType string, params map[string]string, filename string, data []byte) *mimePart {
+	if params == nil {
+		params = map[string]string{}
+	}
+	params["name"] = filename
+
+	p := &mimePart{
+		body: func(w io.Writer) error {
+			encoder := base64.NewEncoder(base64.StdEncoding, &lineWrapper{w: w, max: 76})
+			if _, err := encoder.Write(data); err != nil {
+				return err
+			}
+			if err := encoder.Close(); err != nil {
+				return err
+			}
+			_, err := w.Write(crlf)
+			return err
+		},
+	}
+
+	p.header.add("Content-Type", mime.FormatMediaType(mediaType, params))
+	p.header.add("Content-Transfer-Encoding", "base64")
+	p.header.add("Content-Disposition", mime.FormatMediaType(DispositionAttachment, map[string]string{"filename": filename}))
+
+	return p
+}
+
+// renderEntity 将 MIME 实体渲染为字节
+func renderEntity(p *mimePart) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := p.writeTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
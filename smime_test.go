@@ -0,0 +1,325 @@
+package gomailer
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"io"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smallstep/pkcs7"
+)
+
+// smimeIdentity 是测试用的证书和私钥
+type smimeIdentity struct {
+	cert *x509.Certificate
+	key  *rsa.PrivateKey
+}
+
+// newSMIMEIdentity 为 address 生成自签名的 S/MIME 证书
+func newSMIMEIdentity(t *testing.T, address string) smimeIdentity {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:   serial,
+		Subject:        pkix.Name{CommonName: address},
+		EmailAddresses: []string{address},
+		NotBefore:      time.Now().Add(-time.Hour),
+		NotAfter:       time.Now().Add(time.Hour),
+		KeyUsage:       x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageEmailProtection},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return smimeIdentity{cert: cert, key: key}
+}
+
+// smimeEntity 是一个渲染后的纯文本正文实体
+const smimeEntity = "Content-Type: text/plain; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: 7bit\r\n" +
+	"\r\n" +
+	"hello\r\n"
+
+// readEntity 拆分实体的头部和内容
+func readEntity(t *testing.T, entity []byte) (textproto.MIMEHeader, []byte) {
+	t.Helper()
+
+	r := bufio.NewReader(bytes.NewReader(entity))
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		t.Fatalf("read entity header: %v", err)
+	}
+	body, _ := io.ReadAll(r)
+	return header, body
+}
+
+// decodePKCS7Part 解码 base64 编码的 application/pkcs7-* 实体
+func decodePKCS7Part(t *testing.T, entity []byte, wantType string) []byte {
+	t.Helper()
+
+	header, body := readEntity(t, entity)
+	if mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type")); mediaType != wantType {
+		t.Fatalf("Content-Type = %q, want %s", header.Get("Content-Type"), wantType)
+	}
+
+	der, err := base64.StdEncoding.DecodeString(strings.NewReplacer("\r", "", "\n", "").Replace(string(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+// verifySigned 检查 multipart/signed 实体的签名，返回被签名的第一部分
+func verifySigned(t *testing.T, entity []byte, signer *x509.Certificate) []byte {
+	t.Helper()
+
+	header, body := readEntity(t, entity)
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/signed" {
+		t.Fatalf("Content-Type = %q", header.Get("Content-Type"))
+	}
+	if params["protocol"] != "application/pkcs7-signature" || params["micalg"] != "sha-256" {
+		t.Errorf("Content-Type params = %v", params)
+	}
+
+	// 被签名的内容是第一个分隔行之后到下一个分隔行之前的原始字节
+	delimiter := "--" + params["boundary"]
+	start := bytes.Index(body, []byte(delimiter+"\r\n"))
+	end := bytes.Index(body, []byte("\r\n"+delimiter+"\r\n"))
+	if start < 0 || end < start {
+		t.Fatalf("cannot find first part in %q", body)
+	}
+	signedPart := body[start+len(delimiter)+2 : end]
+
+	mr := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	if _, err := mr.NextPart(); err != nil {
+		t.Fatal(err)
+	}
+	sigPart, err := mr.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := io.ReadAll(sigPart)
+
+	var sigEntity bytes.Buffer
+	for k, v := range sigPart.Header {
+		sigEntity.WriteString(k + ": " + v[0] + "\r\n")
+	}
+	sigEntity.WriteString("\r\n")
+	sigEntity.Write(raw)
+
+	p7, err := pkcs7.Parse(decodePKCS7Part(t, sigEntity.Bytes(), "application/pkcs7-signature"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p7.Content = signedPart
+	if err := p7.Verify(); err != nil {
+		t.Fatalf("signature does not verify: %v", err)
+	}
+	if signers := p7.GetOnlySigner(); signers == nil || !signers.Equal(signer) {
+		t.Errorf("signer = %v, want %v", signers, signer.Subject)
+	}
+
+	return signedPart
+}
+
+// envelopedRecipients 返回 EnvelopedData 中每个 RecipientInfo 的证书序列号
+func envelopedRecipients(t *testing.T, der []byte) []*big.Int {
+	t.Helper()
+
+	var info cmsContentInfo
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		t.Fatal(err)
+	}
+	if !info.ContentType.Equal(oidEnvelopedData) {
+		t.Fatalf("content type = %v", info.ContentType)
+	}
+
+	var enveloped cmsEnvelopedData
+	if _, err := asn1.Unmarshal(info.Content.Bytes, &enveloped); err != nil {
+		t.Fatal(err)
+	}
+
+	serials := make([]*big.Int, len(enveloped.RecipientInfos))
+	for i, ri := range enveloped.RecipientInfos {
+		serials[i] = ri.IssuerAndSerialNumber.SerialNumber
+	}
+	return serials
+}
+
+func TestSMIMESign(t *testing.T) {
+	sender := newSMIMEIdentity(t, "sender@example.com")
+	s := &SMIME{Certificate: sender.cert, PrivateKey: sender.key}
+
+	env := &Envelope{From: "sender@example.com", Recipients: []string{"to@example.com"}}
+	signed, err := s.Transform(messageTo("to@example.com"), env, []byte(smimeEntity))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := verifySigned(t, signed, sender.cert); string(got) != smimeEntity {
+		t.Errorf("signed part = %q, want the original entity", got)
+	}
+}
+
+func TestSMIMEEncrypt(t *testing.T) {
+	to := newSMIMEIdentity(t, "to@example.com")
+	cc := newSMIMEIdentity(t, "cc@example.com")
+	s := &SMIME{
+		Encrypt: true,
+		RecipientCertificates: map[string]*x509.Certificate{
+			"To@Example.com": to.cert,
+			"cc@example.com": cc.cert,
+		},
+	}
+
+	env := &Envelope{From: "sender@example.com", Recipients: []string{"to@example.com", "cc@example.com"}}
+	encrypted, err := s.Transform(messageTo("to@example.com", "cc@example.com"), env, []byte(smimeEntity))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header, _ := readEntity(t, encrypted)
+	if _, params, _ := mime.ParseMediaType(header.Get("Content-Type")); params["smime-type"] != "enveloped-data" {
+		t.Errorf("Content-Type = %q", header.Get("Content-Type"))
+	}
+
+	der := decodePKCS7Part(t, encrypted, "application/pkcs7-mime")
+
+	// 每个收件人都能用自己的私钥解密
+	for _, id := range []smimeIdentity{to, cc} {
+		p7, err := pkcs7.Parse(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		plain, err := p7.Decrypt(id.cert, id.key)
+		if err != nil {
+			t.Fatalf("%s: decrypt: %v", id.cert.Subject.CommonName, err)
+		}
+		if string(plain) != smimeEntity {
+			t.Errorf("%s: decrypted = %q", id.cert.Subject.CommonName, plain)
+		}
+	}
+
+	if n := len(envelopedRecipients(t, der)); n != 2 {
+		t.Errorf("RecipientInfos = %d, want 2", n)
+	}
+
+	// 缺少证书的收件人
+	env.Recipients = append(env.Recipients, "other@example.com")
+	if _, err := s.Transform(messageTo("to@example.com"), env, []byte(smimeEntity)); err == nil || !strings.Contains(err.Error(), "other@example.com") {
+		t.Errorf("err = %v, want missing certificate error", err)
+	}
+}
+
+func TestSMIMESignAndEncrypt(t *testing.T) {
+	sender := newSMIMEIdentity(t, "sender@example.com")
+	to := newSMIMEIdentity(t, "to@example.com")
+	s := &SMIME{
+		Certificate:           sender.cert,
+		PrivateKey:            sender.key,
+		Encrypt:               true,
+		RecipientCertificates: map[string]*x509.Certificate{"to@example.com": to.cert},
+	}
+
+	env := &Envelope{From: "sender@example.com", Recipients: []string{"to@example.com"}}
+	entity, err := s.Transform(messageTo("to@example.com"), env, []byte(smimeEntity))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p7, err := pkcs7.Parse(decodePKCS7Part(t, entity, "application/pkcs7-mime"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := p7.Decrypt(to.cert, to.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 先签名再加密：解密后得到 multipart/signed
+	if got := verifySigned(t, plain, sender.cert); string(got) != smimeEntity {
+		t.Errorf("signed part = %q", got)
+	}
+}
+
+func TestSMIMEEncryptRejectsBcc(t *testing.T) {
+	to := newSMIMEIdentity(t, "to@example.com")
+	bcc := newSMIMEIdentity(t, "hidden@example.com")
+
+	f := newFakeSMTP(t)
+	client := f.client()
+	client.Transformers = []Transformer{&SMIME{
+		Encrypt: true,
+		RecipientCertificates: map[string]*x509.Certificate{
+			"to@example.com":     to.cert,
+			"hidden@example.com": bcc.cert,
+		},
+	}}
+
+	m := messageTo("to@example.com")
+	m.Bcc = []mail.Address{{Address: "Hidden@example.com"}}
+
+	err := client.Send(m)
+	if !errors.Is(err, ErrInvalidMessage) {
+		t.Fatalf("err = %v, want ErrInvalidMessage", err)
+	}
+	var errs ValidationErrors
+	if !errors.As(err, &errs) || errs[0].Field != "bcc" {
+		t.Errorf("errors = %v, want a bcc error", err)
+	}
+	if n := f.connections(); n != 0 {
+		t.Errorf("connections = %d, want 0", n)
+	}
+
+	// 单独发给 Bcc 收件人的邮件只包含 Bcc 收件人自己的证书
+	m.Bcc = nil
+	if err := client.Send(m); err != nil {
+		t.Fatal(err)
+	}
+	m.To = []mail.Address{{Address: "hidden@example.com"}}
+	if err := client.Send(m); err != nil {
+		t.Fatal(err)
+	}
+
+	txns := f.transactions()
+	if len(txns) != 2 {
+		t.Fatalf("transactions = %d, want 2", len(txns))
+	}
+	for i, want := range []*x509.Certificate{to.cert, bcc.cert} {
+		serials := envelopedRecipients(t, decodePKCS7Part(t, []byte(txns[i].Data), "application/pkcs7-mime"))
+		if len(serials) != 1 || serials[0].Cmp(want.SerialNumber) != 0 {
+			t.Errorf("message %d: RecipientInfos = %v, want only %v", i, serials, want.SerialNumber)
+		}
+	}
+}
@@ -1,12 +1,14 @@
 package gomailer
 
 import (
+    "crypto/tls"
     "errors"
     "fmt"
+    "net"
     "net/smtp"
+    "net/textproto"
+    "strconv"
     "strings"
-
-    "github.com/domodwyer/mailyak/v3"
 )
 
 // 确保 SMTPClient 实现了 Mailer 接口
@@ -25,6 +27,9 @@ type SMTPClient struct {
 	// onSend 发送钩子，允许在发送前后执行自定义逻辑
 	onSend *Hook[*SendEvent]
 
+	// onSendRaw 原始邮件（SendRaw）的发送钩子
+	onSendRaw *Hook[*SendRawEvent]
+
 	// TLS 是否使用 TLS 加密连接
 	TLS bool
 
@@ -49,6 +54,25 @@ type SMTPClient struct {
 	// 如果未明确设置，默认为 "localhost"
 	// 某些 SMTP 服务器需要此设置，例如 Gmail SMTP-relay
 	LocalName string
+
+	// MaxRecipientsPerMessage 单个 SMTP 事务允许的最大收件人数（RCPT TO 数量）
+	// 收件人超过此数量时会在同一连接上拆分为多个事务发送，邮件头保持一致且不暴露 Bcc
+	// 如果未明确设置（0），所有收件人在一个事务中发送
+	MaxRecipientsPerMessage int
+
+	// MaxMessageSize 客户端允许发送的最大邮件大小（编码后的字节数）
+	// 超出时在连接服务器之前直接返回 *MessageSizeError
+	// 服务器通过 SIZE 扩展声明的限制总是会被额外检查
+	// 如果未明确设置（0），不做客户端限制
+	MaxMessageSize int64
+
+	// MessageIDDomain 自动生成 Message-ID 时使用的域名
+	// 如果未明确设置，使用发件人地址的域名
+	MessageIDDomain string
+
+	// Transformers 在发送前依次对渲染后的正文进行转换（如 S/MIME 签名和加密）
+	// 不会作用于 SendRaw 发送的原始邮件
+	Transformers []Transformer
 }
 
 // OnSend 实现 SendInterceptor 接口
@@ -75,141 +99,468 @@ func (c *SMTPClient) OnSend() *Hook[*SendEvent] {
 // 返回:
 //   - error: 发送失败时返回错误，成功返回 nil
 func (c *SMTPClient) Send(m *Message) error {
+	// 邮件为 nil 时直接返回校验错误，避免生成 Message-ID 时解引用
+	if m == nil {
+		return m.Validate()
+	}
+
+	id, err := ensureMessageID(m, c.MessageIDDomain)
+	if err != nil {
+		return err
+	}
+
+	return c.sendWith(m, id, c.deliver)
+}
+
+// deliverFunc 将渲染后的邮件投递给信封收件人
+type deliverFunc func(from string, recipients []string, body messageContent) error
+
+// sendWith 触发发送钩子并通过 deliver 投递邮件，SMTPClient 和 SMTPSession 共用
+func (c *SMTPClient) sendWith(m *Message, id string, deliver deliverFunc) error {
 	if c.onSend != nil {
-		return c.onSend.Trigger(&SendEvent{Message: m}, func(e *SendEvent) error {
-			return c.send(e.Message)
+		return c.onSend.Trigger(&SendEvent{Message: m, MessageID: id}, func(e *SendEvent) error {
+			// 钩子可能替换了邮件，邮件自身的 Message-ID 优先
+			if own := messageIDOf(e.Message); own != "" {
+				e.MessageID = own
+			}
+			return c.send(e.Message, e.MessageID, deliver)
 		})
 	}
 
-	return c.send(m)
+	return c.send(m, id, deliver)
 }
 
 // send 内部发送方法，执行实际的 SMTP 发送操作
-func (c *SMTPClient) send(m *Message) error {
-    // 基础输入校验
-    if m == nil {
-        return errors.New("message is nil")
-    }
-    if m.From.Address == "" {
-        return errors.New("from address is required")
-    }
-    if len(m.To) == 0 && len(m.Cc) == 0 && len(m.Bcc) == 0 {
-        return errors.New("at least one recipient (To/Cc/Bcc) is required")
-    }
-
-    // 配置 SMTP 认证
-    var smtpAuth smtp.Auth
-    if c.Username != "" || c.Password != "" {
-        if c.Username == "" || c.Password == "" {
-            return errors.New("both username and password are required when using SMTP auth")
-        }
-        switch c.AuthMethod {
-        case SMTPAuthLogin:
-            // 使用 LOGIN 认证（某些服务如 Outlook 需要）
-            smtpAuth = &smtpLoginAuth{c.Username, c.Password}
-        default:
-            // 默认使用 PLAIN 认证
-            smtpAuth = smtp.PlainAuth("", c.Username, c.Password, c.Host)
-        }
-    }
-
-    // 创建 MailYak 实例
-    var yak *mailyak.MailYak
-    if c.TLS {
-        // 465 端口通常为隐式 TLS，其它端口（如 587）使用 STARTTLS
-        if c.Port == 465 {
-            var tlsErr error
-            yak, tlsErr = mailyak.NewWithTLS(fmt.Sprintf("%s:%d", c.Host, c.Port), smtpAuth, nil)
-            if tlsErr != nil {
-                return tlsErr
-            }
-        } else {
-            // 587/25 等端口：使用常规连接，由 MailYak 进行 STARTTLS（若可用）
-            yak = mailyak.New(fmt.Sprintf("%s:%d", c.Host, c.Port), smtpAuth)
-        }
-    } else {
-        // 明确关闭 TLS：使用常规连接
-        yak = mailyak.New(fmt.Sprintf("%s:%d", c.Host, c.Port), smtpAuth)
-    }
+func (c *SMTPClient) send(m *Message, messageID string, deliver deliverFunc) error {
+	// 校验邮件消息
+	if err := m.Validate(); err != nil {
+		return err
+	}
 
-	// 设置本地主机名（如果指定）
-	if c.LocalName != "" {
-		yak.LocalName(c.LocalName)
+	// 构建邮件内容（所有事务共用同一份内容，Bcc 不会出现在邮件头中）
+	body, err := c.buildMime(m, messageID)
+	if err != nil {
+		return err
+	}
+
+	// 信封收件人：To、Cc 和 Bcc 的邮箱地址
+	recipients := make([]string, 0, len(m.To)+len(m.Cc)+len(m.Bcc))
+	recipients = append(recipients, addressesToStrings(m.To, false)...)
+	recipients = append(recipients, addressesToStrings(m.Cc, false)...)
+	recipients = append(recipients, addressesToStrings(m.Bcc, false)...)
+
+	env := &Envelope{From: m.From.Address, Recipients: recipients}
+	if err := body.transform(c.Transformers, m, env); err != nil {
+		return err
 	}
 
-	// 设置发件人信息
-	if m.From.Name != "" {
-		yak.FromName(m.From.Name)
+	return deliver(env.From, env.Recipients, body)
+}
+
+// deliver 连接服务器并将内容投递给信封收件人，Send 和 SendRaw 共用
+//
+// 收件人会按 MaxRecipientsPerMessage 拆分为多个事务，部分收件人失败时返回 *DeliveryError
+func (c *SMTPClient) deliver(from string, recipients []string, body messageContent) error {
+	if err := c.checkSize(body); err != nil {
+		return err
+	}
+
+	client, err := c.connect()
+	if err != nil {
+		return err
 	}
-	yak.From(m.From.Address)
-	yak.Subject(m.Subject)
-	yak.HTML().Set(m.HTML)
+	defer client.Close()
 
-	// 设置纯文本内容
-	if m.Text == "" {
-		// 尝试从 HTML 自动生成纯文本版本
-		if plain, err := html2Text(m.HTML); err == nil {
-			yak.Plain().Set(plain)
+	if _, err := c.deliverOn(client, from, recipients, body); err != nil {
+		return err
+	}
+
+	// 邮件已全部投递，QUIT 失败不影响结果
+	_ = client.Quit()
+	return nil
+}
+
+// checkSize 检查邮件是否超过 MaxMessageSize
+func (c *SMTPClient) checkSize(body messageContent) error {
+	if c.MaxMessageSize <= 0 {
+		return nil
+	}
+
+	size, err := body.Size()
+	if err != nil {
+		return err
+	}
+	if size > c.MaxMessageSize {
+		return &MessageSizeError{Size: size, Limit: c.MaxMessageSize}
+	}
+
+	return nil
+}
+
+// connect 根据配置的认证方式连接服务器
+func (c *SMTPClient) connect() (*smtp.Client, error) {
+	var smtpAuth smtp.Auth
+	if c.Username != "" || c.Password != "" {
+		if c.Username == "" || c.Password == "" {
+			return nil, errors.New("both username and password are required when using SMTP auth")
+		}
+		switch c.AuthMethod {
+		case SMTPAuthLogin:
+			// 使用 LOGIN 认证（某些服务如 Outlook 需要）
+			smtpAuth = &smtpLoginAuth{c.Username, c.Password}
+		default:
+			// 默认使用 PLAIN 认证
+			smtpAuth = smtp.PlainAuth("", c.Username, c.Password, c.Host)
 		}
+	}
+
+	return c.dial(smtpAuth)
+}
+
+// deliverOn 在已建立的连接上投递邮件
+//
+// 返回:
+//   - bool: 连接是否仍然可以用于下一封邮件
+//   - error: 部分或全部收件人失败时返回 *DeliveryError，其它错误原样返回
+func (c *SMTPClient) deliverOn(client *smtp.Client, from string, recipients []string, body messageContent) (bool, error) {
+	params, err := mailParams(client, body)
+	if err != nil {
+		// 尚未与服务器通信，连接仍然可用
+		return true, err
+	}
+	rcptParams := rcptParams(client, body)
+
+	var failed []*RecipientError
+	usable := true
+
+	batches := splitRecipients(recipients, c.MaxRecipientsPerMessage)
+	for i, batch := range batches {
+		rejected, fatalErr := c.transaction(client, from, params, rcptParams, batch, body)
+		failed = append(failed, rejected...)
+
+		if fatalErr != nil {
+			usable = false
+
+			// 连接已不可用，剩余批次的收件人全部记为失败
+			for _, rest := range batches[i+1:] {
+				for _, rcpt := range rest {
+					failed = append(failed, &RecipientError{Address: rcpt, Err: fatalErr})
+				}
+			}
+			break
+		}
+	}
+
+	if len(failed) == 0 {
+		return true, nil
+	}
+
+	return usable, &DeliveryError{Recipients: failed, Total: len(recipients)}
+}
+
+// buildMime 构建邮件头和 MIME 结构，附件内容在写入 DATA 时才流式读取
+func (c *SMTPClient) buildMime(m *Message, messageID string) (*renderedMessage, error) {
+	header, err := buildHeader(m, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	return renderMessage(header, m)
+}
+
+// dial 连接 SMTP 服务器并完成 EHLO、TLS 和认证
+//
+// 465 端口且 TLS 为 true 时使用隐式 TLS，其它情况下如果服务器支持则通过 STARTTLS 升级连接
+func (c *SMTPClient) dial(auth smtp.Auth) (*smtp.Client, error) {
+	addr := net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+
+	var conn net.Conn
+	var err error
+	implicitTLS := c.TLS && c.Port == 465
+	if implicitTLS {
+		conn, err = tls.Dial("tcp", addr, &tls.Config{ServerName: c.Host})
 	} else {
-		yak.Plain().Set(m.Text)
+		conn, err = net.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	// 设置收件人
-	if len(m.To) > 0 {
-		yak.To(addressesToStrings(m.To, true)...)
+	client, err := smtp.NewClient(conn, c.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
 	}
 
-	// 设置密送收件人
-	if len(m.Bcc) > 0 {
-		yak.Bcc(addressesToStrings(m.Bcc, true)...)
+	// 设置本地主机名（如果指定）
+	if c.LocalName != "" {
+		if err := client.Hello(c.LocalName); err != nil {
+			client.Close()
+			return nil, err
+		}
 	}
 
-	// 设置抄送收件人
-	if len(m.Cc) > 0 {
-		yak.Cc(addressesToStrings(m.Cc, true)...)
+	if !implicitTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: c.Host}); err != nil {
+				client.Close()
+				return nil, err
+			}
+		}
 	}
 
-	// 添加普通附件
-	for name, data := range m.Attachments {
-		r, mime, err := detectReaderMimeType(data)
-		if err != nil {
-			return err
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			client.Close()
+			return nil, err
 		}
-		yak.AttachWithMimeType(name, r, mime)
 	}
 
-	// 添加内联附件（用于 HTML 中的嵌入图片等）
-	for name, data := range m.InlineAttachments {
-		r, mime, err := detectReaderMimeType(data)
-		if err != nil {
-			return err
+	return client, nil
+}
+
+// transaction 在已建立的连接上执行一次 MAIL/RCPT/DATA 事务
+//
+// 返回:
+//   - []*RecipientError: 本次事务中未能投递的收件人及原因
+//   - error: 连接级错误（此时连接不可再用），服务器的拒绝响应不会通过此值返回
+func (c *SMTPClient) transaction(client *smtp.Client, from string, params, rcptParams []string, recipients []string, body messageContent) ([]*RecipientError, error) {
+	// failAll 将整批收件人标记为失败
+	failAll := func(list []string, err error) []*RecipientError {
+		result := make([]*RecipientError, 0, len(list))
+		for _, rcpt := range list {
+			result = append(result, &RecipientError{Address: rcpt, Err: err})
 		}
-		yak.AttachInlineWithMimeType(name, r, mime)
+		return result
+	}
+
+	if err := mailFrom(client, from, params); err != nil {
+		return failAll(recipients, sizeRejection(err, body)), resetAfter(client, err)
+	}
+
+	var rejected []*RecipientError
+	accepted := make([]string, 0, len(recipients))
+	for _, rcpt := range recipients {
+		if err := rcptTo(client, rcpt, rcptParams); err != nil {
+			if !isSMTPReply(err) {
+				return append(rejected, failAll(recipients[len(accepted)+len(rejected):], err)...), err
+			}
+			rejected = append(rejected, &RecipientError{Address: rcpt, Err: err})
+			continue
+		}
+		accepted = append(accepted, rcpt)
+	}
+
+	// 没有任何收件人被接受时不能进入 DATA 阶段
+	if len(accepted) == 0 {
+		return rejected, resetAfter(client, nil)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return append(rejected, failAll(accepted, err)...), resetAfter(client, err)
 	}
 
-	// 添加自定义邮件头
-	var hasMessageId bool
-	for k, v := range m.Headers {
-		if strings.EqualFold(k, "Message-ID") {
-			hasMessageId = true
+	// 写入失败时不能关闭 w，否则会以 "." 结束 DATA 并投递不完整的邮件；
+	// 直接作为连接级错误返回，由调用方关闭连接使服务器丢弃本次事务
+	if _, err := body.WriteTo(w); err != nil {
+		return append(rejected, failAll(accepted, err)...), err
+	}
+
+	if err := w.Close(); err != nil {
+		return append(rejected, failAll(accepted, sizeRejection(err, body))...), resetAfter(client, err)
+	}
+
+	return rejected, nil
+}
+
+// mailParams 根据服务器声明的扩展生成 MAIL FROM 命令的参数
+//
+// 服务器支持 SIZE 扩展时声明邮件大小，并在超过服务器声明的上限时直接返回 *MessageSizeError，
+// 避免上传完整内容后才被拒绝
+func mailParams(client *smtp.Client, body messageContent) ([]string, error) {
+	var params []string
+
+	// 必须在计算大小之前决定是否使用 8bit，编码不同大小也不同
+	eightBit := false
+	if ok, _ := client.Extension("8BITMIME"); ok {
+		eightBit = body.use8BitMIME()
+	}
+
+	if ok, limit := client.Extension("SIZE"); ok {
+		size, err := body.Size()
+		if err != nil {
+			return nil, err
 		}
-		yak.AddHeader(k, v)
+		if max, err := strconv.ParseInt(strings.TrimSpace(limit), 10, 64); err == nil && max > 0 && size > max {
+			return nil, &MessageSizeError{Size: size, Limit: max}
+		}
+		params = append(params, fmt.Sprintf("SIZE=%d", size))
+	}
+
+	if eightBit {
+		params = append(params, "BODY=8BITMIME")
+	}
+
+	if ok, _ := client.Extension("SMTPUTF8"); ok {
+		params = append(params, "SMTPUTF8")
 	}
 
-	// 如果没有 Message-ID，添加一个默认的
-	if !hasMessageId {
-		fromParts := strings.Split(m.From.Address, "@")
-		if len(fromParts) == 2 {
-			yak.AddHeader("Message-ID", fmt.Sprintf("<%s@%s>",
-				pseudorandomString(15),
-				fromParts[1],
-			))
+	if dsn := body.deliveryNotification(); dsn != nil {
+		if ok, _ := client.Extension("DSN"); ok {
+			params = append(params, dsn.mailParams()...)
 		}
 	}
 
-	// 执行发送
-	return yak.Send()
+	return params, nil
+}
+
+// rcptParams 根据服务器声明的扩展生成 RCPT TO 命令的参数，目前只有 DSN 的 NOTIFY
+func rcptParams(client *smtp.Client, body messageContent) []string {
+	dsn := body.deliveryNotification()
+	if dsn == nil {
+		return nil
+	}
+
+	if ok, _ := client.Extension("DSN"); !ok {
+		return nil
+	}
+
+	return dsn.rcptParams()
+}
+
+// mailFrom 发送带扩展参数的 MAIL FROM 命令
+//
+// net/smtp 的 Client.Mail 不支持 SIZE 等参数，因此这里直接通过底层的 textproto 连接发送
+func mailFrom(client *smtp.Client, from string, params []string) error {
+	return extendedCmd(client, 250, "MAIL FROM:<"+from+">", params)
+}
+
+// rcptTo 发送带扩展参数的 RCPT TO 命令，没有参数时使用 net/smtp 的 Client.Rcpt
+func rcptTo(client *smtp.Client, to string, params []string) error {
+	if len(params) == 0 {
+		return client.Rcpt(to)
+	}
+
+	return extendedCmd(client, 25, "RCPT TO:<"+to+">", params)
+}
+
+// extendedCmd 发送带扩展参数的命令并读取响应，expectCode 的含义与 textproto.Reader.ReadResponse 相同
+func extendedCmd(client *smtp.Client, expectCode int, cmd string, params []string) error {
+	if strings.ContainsAny(cmd, "\r\n") {
+		return errors.New("smtp: A line must not contain CR or LF")
+	}
+
+	if len(params) > 0 {
+		cmd += " " + strings.Join(params, " ")
+	}
+
+	id, err := client.Text.Cmd("%s", cmd)
+	if err != nil {
+		return err
+	}
+
+	client.Text.StartResponse(id)
+	defer client.Text.EndResponse(id)
+
+	_, _, err = client.Text.ReadResponse(expectCode)
+	return err
+}
+
+// sizeRejection 将服务器的 552 响应（超出存储限制）转换为 *MessageSizeError
+func sizeRejection(err error, body messageContent) error {
+	var tpErr *textproto.Error
+	if errors.As(err, &tpErr) && tpErr.Code == 552 {
+		return &MessageSizeError{Size: body.knownSize(), Err: err}
+	}
+
+	return err
+}
+
+// resetAfter 在事务失败后发送 RSET 以便继续使用连接
+//
+// 如果 cause 不是服务器的拒绝响应（即连接级错误），直接返回 cause
+func resetAfter(client *smtp.Client, cause error) error {
+	if cause != nil && !isSMTPReply(cause) {
+		return cause
+	}
+
+	return client.Reset()
+}
+
+// isSMTPReply 判断错误是否为服务器返回的 SMTP 响应（而非网络错误）
+func isSMTPReply(err error) bool {
+	var tpErr *textproto.Error
+	return errors.As(err, &tpErr)
+}
+
+// splitRecipients 按每个事务的最大收件人数拆分收件人列表，max 小于等于 0 时不拆分
+func splitRecipients(recipients []string, max int) [][]string {
+	if max <= 0 || len(recipients) <= max {
+		return [][]string{recipients}
+	}
+
+	batches := make([][]string, 0, (len(recipients)+max-1)/max)
+	for len(recipients) > max {
+		batches = append(batches, recipients[:max])
+		recipients = recipients[max:]
+	}
+
+	return append(batches, recipients)
+}
+
+// -------------------------------------------------------------------
+// 投递结果
+// -------------------------------------------------------------------
+
+// RecipientError 描述了单个收件人的投递失败
+type RecipientError struct {
+	// Address 收件人邮箱地址
+	Address string
+
+	// Err 失败原因（通常是服务器返回的 *textproto.Error）
+	Err error
+}
+
+// Error 实现 error 接口
+func (e *RecipientError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Address, e.Err)
+}
+
+// Unwrap 返回底层错误
+func (e *RecipientError) Unwrap() error {
+	return e.Err
+}
+
+// DeliveryError 表示邮件未能投递给部分或全部收件人
+//
+// 其余收件人已经成功接收邮件，重试时应只针对 Recipients 中列出的地址
+type DeliveryError struct {
+	// Recipients 投递失败的收件人
+	Recipients []*RecipientError
+
+	// Total 信封中的收件人总数
+	Total int
+}
+
+// Error 实现 error 接口
+func (e *DeliveryError) Error() string {
+	parts := make([]string, len(e.Recipients))
+	for i, r := range e.Recipients {
+		parts[i] = r.Error()
+	}
+
+	return fmt.Sprintf("failed to deliver to %d of %d recipients: %s",
+		len(e.Recipients), e.Total, strings.Join(parts, "; "))
+}
+
+// Unwrap 返回所有收件人的错误，便于使用 errors.Is/errors.As 检查
+func (e *DeliveryError) Unwrap() []error {
+	errs := make([]error, len(e.Recipients))
+	for i, r := range e.Recipients {
+		errs[i] = r
+	}
+	return errs
 }
 
 // -------------------------------------------------------------------
@@ -0,0 +1,289 @@
+package gomailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/textproto"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSMTPClientRecipientRejects(t *testing.T) {
+	f := newFakeSMTP(t)
+	f.Reject = map[string]string{"missing@example.com": "550 5.1.1 no such user"}
+
+	m := &Message{
+		From:    mail.Address{Address: "sender@example.com"},
+		To:      []mail.Address{{Address: "a@example.com"}, {Address: "missing@example.com"}},
+		Bcc:     []mail.Address{{Address: "b@example.com"}},
+		Subject: "partial",
+		Text:    "hello",
+	}
+
+	err := f.client().Send(m)
+
+	var deliveryErr *DeliveryError
+	if !errors.As(err, &deliveryErr) {
+		t.Fatalf("err = %v, want *DeliveryError", err)
+	}
+	if deliveryErr.Total != 3 || len(deliveryErr.Recipients) != 1 || deliveryErr.Recipients[0].Address != "missing@example.com" {
+		t.Fatalf("unexpected delivery error: %v", deliveryErr)
+	}
+
+	var tpErr *textproto.Error
+	if !errors.As(err, &tpErr) || tpErr.Code != 550 {
+		t.Errorf("err does not wrap the 550 reply: %v", err)
+	}
+
+	txns := f.transactions()
+	if len(txns) != 1 || !reflect.DeepEqual(txns[0].Rcpts, []string{"a@example.com", "b@example.com"}) {
+		t.Errorf("accepted recipients were not delivered: %+v", txns)
+	}
+}
+
+func TestSMTPClientAllRecipientsRejectedResetsTransaction(t *testing.T) {
+	f := newFakeSMTP(t)
+	f.Reject = map[string]string{
+		"x@example.com": "550 no such user",
+		"y@example.com": "550 no such user",
+	}
+
+	m := &Message{
+		From: mail.Address{Address: "sender@example.com"},
+		To:   []mail.Address{{Address: "x@example.com"}, {Address: "y@example.com"}},
+		Text: "hello",
+	}
+
+	var deliveryErr *DeliveryError
+	if err := f.client().Send(m); !errors.As(err, &deliveryErr) || len(deliveryErr.Recipients) != 2 {
+		t.Fatalf("err = %v, want *DeliveryError for both recipients", err)
+	}
+
+	if len(f.transactions()) != 0 {
+		t.Error("DATA must not be sent without accepted recipients")
+	}
+	if f.resets() == 0 {
+		t.Error("expected RSET after the failed transaction")
+	}
+}
+
+func TestSMTPClientMaxRecipientsPerMessage(t *testing.T) {
+	f := newFakeSMTP(t)
+	f.MaxRcpt = 2
+
+	m := &Message{
+		From:    mail.Address{Address: "sender@example.com"},
+		To:      []mail.Address{{Address: "to@example.com"}},
+		Cc:      []mail.Address{{Address: "cc@example.com"}},
+		Subject: "announcement",
+		Text:    "hello",
+	}
+	for i := 0; i < 5; i++ {
+		m.Bcc = append(m.Bcc, mail.Address{Address: fmt.Sprintf("bcc%d@example.com", i)})
+	}
+
+	c := f.client()
+	c.MaxRecipientsPerMessage = 2
+	if err := c.Send(m); err != nil {
+		t.Fatal(err)
+	}
+
+	txns := f.transactions()
+	if len(txns) != 4 {
+		t.Fatalf("transactions = %d, want 4", len(txns))
+	}
+	if f.connections() != 1 {
+		t.Errorf("connections = %d, want all batches on one connection", f.connections())
+	}
+
+	var rcpts []string
+	for _, txn := range txns {
+		if len(txn.Rcpts) > 2 {
+			t.Errorf("batch has %d recipients", len(txn.Rcpts))
+		}
+		if txn.Data != txns[0].Data {
+			t.Error("batches must send identical content")
+		}
+		rcpts = append(rcpts, txn.Rcpts...)
+	}
+
+	want := []string{"to@example.com", "cc@example.com", "bcc0@example.com", "bcc1@example.com", "bcc2@example.com", "bcc3@example.com", "bcc4@example.com"}
+	if !reflect.DeepEqual(rcpts, want) {
+		t.Errorf("recipients = %v, want %v", rcpts, want)
+	}
+
+	h := readHeader(t, txns[0].Data)
+	if h.Get("Bcc") != "" || strings.Contains(txns[0].Data, "bcc0@") {
+		t.Error("Bcc recipients must not appear in the message")
+	}
+	if h.Get("To") != "to@example.com" || h.Get("Cc") != "cc@example.com" {
+		t.Errorf("To/Cc = %q/%q", h.Get("To"), h.Get("Cc"))
+	}
+}
+
+func TestSMTPClientSizeLimits(t *testing.T) {
+	m := &Message{
+		From: mail.Address{Address: "sender@example.com"},
+		To:   []mail.Address{{Address: "to@example.com"}},
+		Text: strings.Repeat("0123456789\n", 200),
+	}
+
+	t.Run("server SIZE", func(t *testing.T) {
+		f := newFakeSMTP(t, "SIZE 1000")
+
+		var sizeErr *MessageSizeError
+		err := f.client().Send(m)
+		if !errors.As(err, &sizeErr) || !errors.Is(err, ErrMessageTooLarge) {
+			t.Fatalf("err = %v, want *MessageSizeError", err)
+		}
+		if sizeErr.Limit != 1000 || sizeErr.Size <= 1000 {
+			t.Errorf("size error = %+v", sizeErr)
+		}
+		if len(f.transactions()) != 0 {
+			t.Error("message must not be sent")
+		}
+	})
+
+	t.Run("declared size", func(t *testing.T) {
+		f := newFakeSMTP(t, "SIZE 1000000")
+		if err := f.client().Send(m); err != nil {
+			t.Fatal(err)
+		}
+
+		txn := f.transactions()[0]
+		if len(txn.FromParams) != 1 || !strings.HasPrefix(txn.FromParams[0], "SIZE=") {
+			t.Fatalf("MAIL FROM params = %v", txn.FromParams)
+		}
+
+		// 假服务器读取 DATA 时去掉了行尾的 CR 和结尾的 "."
+		wire := len(strings.ReplaceAll(txn.Data, "\n", "\r\n"))
+		if want := fmt.Sprintf("SIZE=%d", wire); txn.FromParams[0] != want {
+			t.Errorf("declared %s, sent %d bytes", txn.FromParams[0], wire)
+		}
+	})
+
+	t.Run("client MaxMessageSize", func(t *testing.T) {
+		f := newFakeSMTP(t)
+		c := f.client()
+		c.MaxMessageSize = 500
+
+		if err := c.Send(m); !errors.Is(err, ErrMessageTooLarge) {
+			t.Fatalf("err = %v, want ErrMessageTooLarge", err)
+		}
+		if f.connections() != 0 {
+			t.Error("client limit must be checked before connecting")
+		}
+	})
+
+	t.Run("552 after DATA", func(t *testing.T) {
+		f := newFakeSMTP(t)
+		f.DataReply = "552 5.3.4 message too big"
+
+		var sizeErr *MessageSizeError
+		if err := f.client().Send(m); !errors.As(err, &sizeErr) {
+			t.Fatalf("err = %v, want *MessageSizeError", err)
+		}
+		if sizeErr.Err == nil {
+			t.Error("size error should wrap the server reply")
+		}
+	})
+}
+
+func TestSMTPClient8BitMIME(t *testing.T) {
+	tests := []struct {
+		name     string
+		ext      []string
+		text     string
+		wantBody bool
+		wantCTE  string
+	}{
+		{"ascii", []string{"8BITMIME"}, "hello", false, "7bit"},
+		{"non-ascii with 8BITMIME", []string{"8BITMIME"}, "Viele Grüße aus Köln, bis nächste Woche", true, "8bit"},
+		{"non-ascii without 8BITMIME", nil, "Viele Grüße aus Köln, bis nächste Woche", false, "quoted-printable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeSMTP(t, tt.ext...)
+			m := &Message{
+				From: mail.Address{Address: "sender@example.com"},
+				To:   []mail.Address{{Address: "to@example.com"}},
+				Text: tt.text,
+			}
+			if err := f.client().Send(m); err != nil {
+				t.Fatal(err)
+			}
+
+			txn := f.transactions()[0]
+			hasBody := false
+			for _, p := range txn.FromParams {
+				hasBody = hasBody || p == "BODY=8BITMIME"
+			}
+			if hasBody != tt.wantBody {
+				t.Errorf("BODY=8BITMIME declared = %v, want %v", hasBody, tt.wantBody)
+			}
+
+			if cte := readHeader(t, txn.Data).Get("Content-Transfer-Encoding"); cte != tt.wantCTE {
+				t.Errorf("Content-Transfer-Encoding = %q, want %q", cte, tt.wantCTE)
+			}
+		})
+	}
+}
+
+func TestSplitRecipients(t *testing.T) {
+	tests := []struct {
+		n, max int
+		want   []int
+	}{
+		{5, 0, []int{5}},
+		{5, 5, []int{5}},
+		{5, 2, []int{2, 2, 1}},
+		{6, 3, []int{3, 3}},
+	}
+
+	for _, tt := range tests {
+		recipients := make([]string, tt.n)
+		var sizes []int
+		for _, batch := range splitRecipients(recipients, tt.max) {
+			sizes = append(sizes, len(batch))
+		}
+		if !reflect.DeepEqual(sizes, tt.want) {
+			t.Errorf("splitRecipients(%d, %d) = %v, want %v", tt.n, tt.max, sizes, tt.want)
+		}
+	}
+}
+
+func TestSendNilMessage(t *testing.T) {
+	f := newFakeSMTP(t)
+
+	tests := []struct {
+		name   string
+		mailer Mailer
+	}{
+		{"smtp", f.client()},
+		{"smtp session", f.client().NewSession()},
+		{"sendmail", &Sendmail{}},
+		{"rate limited", &RateLimitedMailer{Mailer: f.client()}},
+		{"circuit breaker", &CircuitBreakerMailer{Mailer: f.client()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.mailer.Send(nil); !errors.Is(err, ErrInvalidMessage) {
+				t.Fatalf("err = %v, want ErrInvalidMessage", err)
+			}
+		})
+	}
+
+	result := SendMany(context.Background(), f.client(), []*Message{nil}, 1)
+	if err := result.Results[0].Err; !errors.Is(err, ErrInvalidMessage) {
+		t.Fatalf("SendMany err = %v, want ErrInvalidMessage", err)
+	}
+
+	if n := f.connections(); n != 0 {
+		t.Errorf("connections = %d, want 0", n)
+	}
+}
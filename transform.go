@@ -0,0 +1,107 @@
+package gomailer
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+)
+
+// Transformer 在邮件渲染完成之后、交给传输发送之前转换邮件正文，例如签名或加密
+//
+// 转换只作用于正文实体（Content-Type 等实体头和内容），From、To、Subject 等顶层邮件头保持不变。
+// 通过 SMTPClient.Transformers 或 Sendmail.Transformers 配置，按顺序依次执行
+type Transformer interface {
+	// Transform 转换正文实体
+	//
+	// 参数:
+	//   - m: 正在发送的邮件（只读）
+	//   - env: 本次发送的信封，转换可以从 env.Recipients 中移除收件人
+	//   - entity: 渲染后的正文实体（实体头 + 空行 + 内容，CRLF 换行）
+	//
+	// 返回:
+	//   - []byte: 替换原实体的新实体，格式与 entity 相同
+	//   - error: 转换失败时返回错误，邮件不会被发送
+	Transform(m *Message, env *Envelope, entity []byte) ([]byte, error)
+}
+
+// TransformerFunc 将普通函数适配为 Transformer
+type TransformerFunc func(m *Message, env *Envelope, entity []byte) ([]byte, error)
+
+// Transform 实现 Transformer 接口
+func (f TransformerFunc) Transform(m *Message, env *Envelope, entity []byte) ([]byte, error) {
+	return f(m, env, entity)
+}
+
+// Envelope 是一次发送的 SMTP 信封
+type Envelope struct {
+	// From 信封发件人（MAIL FROM）
+	From string
+
+	// Recipients 信封收件人（RCPT TO），包括 To、Cc 和 Bcc
+	Recipients []string
+}
+
+// transform 依次执行 transformers，将转换后的正文缓存在内存中
+//
+// 转换需要完整的正文内容，因此附件会在这里被读取一次
+func (r *renderedMessage) transform(transformers []Transformer, m *Message, env *Envelope) error {
+	if len(transformers) == 0 {
+		return nil
+	}
+
+	var entity bytes.Buffer
+	if err := r.body.writeTo(&entity); err != nil {
+		return err
+	}
+
+	data := entity.Bytes()
+	for _, t := range transformers {
+		transformed, err := t.Transform(m, env, data)
+		if err != nil {
+			return err
+		}
+		data = transformed
+	}
+
+	if len(env.Recipients) == 0 {
+		return errors.New("no recipients left after transform")
+	}
+
+	r.body = &mimePart{raw: data}
+
+	buffered := &bytes.Buffer{}
+	if err := r.write(buffered); err != nil {
+		return err
+	}
+	r.buffered = buffered
+	r.size = int64(buffered.Len())
+
+	return nil
+}
+
+// checkEncryptedBcc 检查加密邮件的信封中是否还有 Bcc 收件人
+//
+// 加密后的实体为每个收件人保存一份内容密钥（S/MIME 的 RecipientInfos、OpenPGP 的 PKESK 包），
+// 其中带有收件人的证书或密钥 ID，所有收件人都能看到，因此 Bcc 收件人会暴露给其他人
+//
+// 参数:
+//   - m: 正在发送的邮件
+//   - env: 本次发送的信封
+//
+// 返回:
+//   - error: 信封中有 Bcc 收件人时返回 ValidationErrors
+func checkEncryptedBcc(m *Message, env *Envelope) error {
+	var errs ValidationErrors
+	for _, bcc := range m.Bcc {
+		for _, rcpt := range env.Recipients {
+			if strings.EqualFold(rcpt, bcc.Address) {
+				errs.add("bcc", "encrypted messages cannot have Bcc recipients: %s would be visible to other recipients; send a separate message to each Bcc recipient", bcc.Address)
+				break
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,318 @@
+package gomailer
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Unsubscribe 描述邮件的退订方式，会生成 List-Unsubscribe（RFC 2369）
+// 和 List-Unsubscribe-Post（RFC 8058）邮件头
+//
+// Gmail、Yahoo 等邮箱要求批量发件人提供一键退订：设置 URL 并将 OneClick 设为 true，
+// 邮箱会直接向 URL 发送 POST 请求，可以配合 UnsubscribeHandler 处理
+type Unsubscribe struct {
+	// URL 退订链接，必须是 https 地址
+	URL string `json:"url,omitempty"`
+
+	// Mailto 退订邮箱地址（如 "unsubscribe@example.com"，也可以是带 subject 参数的 mailto: URI）
+	Mailto string `json:"mailto,omitempty"`
+
+	// OneClick 是否支持一键退订（需要设置 URL），会添加
+	// "List-Unsubscribe-Post: List-Unsubscribe=One-Click"
+	OneClick bool `json:"oneClick,omitempty"`
+}
+
+// mailtoURI 返回 mailto: 形式的退订地址
+func (u *Unsubscribe) mailtoURI() string {
+	if u.Mailto == "" || strings.HasPrefix(strings.ToLower(u.Mailto), "mailto:") {
+		return u.Mailto
+	}
+	return "mailto:" + u.Mailto
+}
+
+// header 返回 List-Unsubscribe 邮件头的值
+func (u *Unsubscribe) header() string {
+	var uris []string
+	if u.URL != "" {
+		uris = append(uris, "<"+u.URL+">")
+	}
+	if mailto := u.mailtoURI(); mailto != "" {
+		uris = append(uris, "<"+mailto+">")
+	}
+	return strings.Join(uris, ", ")
+}
+
+// validate 检查退订设置，问题记录到 errs
+func (u *Unsubscribe) validate(errs *ValidationErrors, field string) {
+	if u.URL == "" && u.Mailto == "" {
+		errs.add(field, "url or mailto is required")
+	}
+
+	if u.URL != "" {
+		parsed, err := url.Parse(u.URL)
+		switch {
+		case err != nil || parsed.Host == "" || strings.ContainsAny(u.URL, "<> \t\r\n"):
+			errs.add(field+".url", "invalid url %q", u.URL)
+		case !strings.EqualFold(parsed.Scheme, "https"):
+			errs.add(field+".url", "url must use https")
+		}
+	}
+
+	if u.Mailto != "" {
+		parsed, err := url.Parse(u.mailtoURI())
+		if err != nil || strings.ContainsAny(u.Mailto, "<> \t\r\n") || !validAddress(parsed.Opaque) {
+			errs.add(field+".mailto", "invalid mailto %q", u.Mailto)
+		}
+	}
+
+	if u.OneClick && u.URL == "" {
+		errs.add(field+".oneClick", "one-click unsubscribe requires url")
+	}
+}
+
+// -------------------------------------------------------------------
+// 退订令牌
+// -------------------------------------------------------------------
+
+var (
+	// ErrInvalidUnsubscribeToken 表示退订令牌格式错误或签名不匹配
+	ErrInvalidUnsubscribeToken = errors.New("invalid unsubscribe token")
+
+	// ErrUnsubscribeTokenExpired 表示退订令牌已过期
+	ErrUnsubscribeTokenExpired = errors.New("unsubscribe token expired")
+)
+
+// unsubscribeEncoding 用于校验令牌的 base64 编码
+var unsubscribeEncoding = base64.RawURLEncoding.Strict()
+
+// UnsubscribeToken 是退订令牌中携带的信息
+type UnsubscribeToken struct {
+	// Recipient 退订的收件人地址
+	Recipient string
+
+	// List 退订的列表或分类（如 "newsletter"），可以为空
+	List string
+
+	// ExpiresAt 过期时间，零值表示永不过期
+	ExpiresAt time.Time
+}
+
+// unsubscribeClaims 是令牌负载的序列化形式
+type unsubscribeClaims struct {
+	Recipient string `json:"r"`
+	List      string `json:"l,omitempty"`
+	Expires   int64  `json:"e,omitempty"`
+}
+
+// UnsubscribeSigner 生成和校验使用 HMAC-SHA256 签名的退订令牌，
+// 令牌可以放在退订链接中，无需在服务端保存状态
+//
+// 示例:
+//
+//	signer := &UnsubscribeSigner{Key: secret, TTL: 90 * 24 * time.Hour}
+//	link, _ := signer.URL("https://example.com/unsubscribe", "user@example.com", "newsletter")
+//	message.Unsubscribe = &Unsubscribe{URL: link, OneClick: true}
+type UnsubscribeSigner struct {
+	// Key HMAC 密钥，建议至少 32 字节
+	Key []byte
+
+	// TTL 令牌有效期，如果未明确设置（0），令牌永不过期
+	TTL time.Duration
+}
+
+// Token 为收件人生成退订令牌
+//
+// 参数:
+//   - recipient: 收件人地址
+//   - list: 列表或分类名称，可以为空
+//
+// 返回:
+//   - string: URL 安全的令牌
+//   - error: 未设置密钥时返回错误
+func (s *UnsubscribeSigner) Token(recipient, list string) (string, error) {
+	if len(s.Key) == 0 {
+		return "", errors.New("unsubscribe signer key is required")
+	}
+
+	claims := unsubscribeClaims{Recipient: recipient, List: list}
+	if s.TTL > 0 {
+		claims.Expires = time.Now().Add(s.TTL).Unix()
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(s.sign(encoded)), nil
+}
+
+// URL 生成带有退订令牌的链接（令牌作为 "token" 查询参数）
+//
+// 参数:
+//   - baseURL: 退订处理地址，如 "https://example.com/unsubscribe"
+//   - recipient: 收件人地址
+//   - list: 列表或分类名称，可以为空
+func (s *UnsubscribeSigner) URL(baseURL, recipient, list string) (string, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := s.Token(recipient, list)
+	if err != nil {
+		return "", err
+	}
+
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+// Verify 校验退订令牌并返回其中的信息
+//
+// 返回:
+//   - *UnsubscribeToken: 令牌中的收件人、列表和过期时间
+//   - error: 令牌无效时返回满足 errors.Is(err, ErrInvalidUnsubscribeToken) 的错误，
+//     过期时返回满足 errors.Is(err, ErrUnsubscribeTokenExpired) 的错误
+func (s *UnsubscribeSigner) Verify(token string) (*UnsubscribeToken, error) {
+	if len(s.Key) == 0 {
+		return nil, errors.New("unsubscribe signer key is required")
+	}
+
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidUnsubscribeToken
+	}
+
+	// 严格解码，拒绝末尾填充位不为零等非规范编码，使每个令牌只有一种合法写法
+	mac, err := unsubscribeEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, s.sign(encoded)) {
+		return nil, ErrInvalidUnsubscribeToken
+	}
+
+	payload, err := unsubscribeEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidUnsubscribeToken
+	}
+
+	var claims unsubscribeClaims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Recipient == "" {
+		return nil, ErrInvalidUnsubscribeToken
+	}
+
+	result := &UnsubscribeToken{Recipient: claims.Recipient, List: claims.List}
+	if claims.Expires > 0 {
+		result.ExpiresAt = time.Unix(claims.Expires, 0)
+		if time.Now().After(result.ExpiresAt) {
+			return result, fmt.Errorf("%w: expired at %s", ErrUnsubscribeTokenExpired, result.ExpiresAt.Format(time.RFC3339))
+		}
+	}
+
+	return result, nil
+}
+
+// sign 计算令牌负载的签名
+func (s *UnsubscribeSigner) sign(payload string) []byte {
+	h := hmac.New(sha256.New, s.Key)
+	h.Write([]byte("gomailer-unsubscribe-v1."))
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
+
+// -------------------------------------------------------------------
+// 一键退订处理
+// -------------------------------------------------------------------
+
+// 确保 UnsubscribeHandler 实现了 http.Handler 接口
+var _ http.Handler = (*UnsubscribeHandler)(nil)
+
+// UnsubscribeHandler 处理退订链接的请求（RFC 8058）
+//
+//   - POST：校验 "token" 参数后调用 OnUnsubscribe，邮箱的一键退订和确认页面的表单都使用 POST
+//   - GET：只返回一个确认页面，不会执行退订，避免被邮件安全网关预取链接时误退订
+//
+// 示例:
+//
+//	http.Handle("/unsubscribe", &UnsubscribeHandler{
+//		Signer: signer,
+//		OnUnsubscribe: func(ctx context.Context, t *UnsubscribeToken) error {
+//			return suppressions.Add(ctx, t.Recipient, t.List)
+//		},
+//	})
+type UnsubscribeHandler struct {
+	// Signer 用于校验令牌
+	Signer *UnsubscribeSigner
+
+	// OnUnsubscribe 令牌校验通过后调用，通常将收件人加入抑制列表；返回错误时响应 500
+	OnUnsubscribe func(ctx context.Context, token *UnsubscribeToken) error
+}
+
+// ServeHTTP 实现 http.Handler 接口
+func (h *UnsubscribeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPost:
+	default:
+		w.Header().Set("Allow", "GET, HEAD, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if h.Signer == nil {
+		http.Error(w, "unsubscribe signer is not configured", http.StatusInternalServerError)
+		return
+	}
+
+	// 令牌只从 URL 中读取，POST 的请求体为 "List-Unsubscribe=One-Click"
+	token := r.URL.Query().Get("token")
+	claims, err := h.Signer.Verify(token)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, ErrUnsubscribeTokenExpired) {
+			status = http.StatusGone
+		}
+		http.Error(w, "invalid or expired unsubscribe link", status)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, unsubscribeConfirmPage, html.EscapeString(claims.Recipient), html.EscapeString(r.URL.RequestURI()))
+		return
+	}
+
+	if h.OnUnsubscribe != nil {
+		if err := h.OnUnsubscribe(r.Context(), claims); err != nil {
+			http.Error(w, "unsubscribe failed", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "You have been unsubscribed.")
+}
+
+// unsubscribeConfirmPage 是 GET 请求返回的确认页面
+const unsubscribeConfirmPage = `<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Unsubscribe</title></head>
+<body>
+<p>Unsubscribe %s?</p>
+<form method="post" action="%s">
+<input type="hidden" name="List-Unsubscribe" value="One-Click">
+<button type="submit">Unsubscribe</button>
+</form>
+</body></html>
+`
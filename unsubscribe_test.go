@@ -0,0 +1,324 @@
+package gomailer
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testUnsubscribeKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestUnsubscribeSignerRoundTrip(t *testing.T) {
+	s := &UnsubscribeSigner{Key: testUnsubscribeKey, TTL: time.Hour}
+
+	token, err := s.Token("user@example.com", "newsletter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url.QueryEscape(token) != token {
+		t.Errorf("token %q is not URL safe", token)
+	}
+
+	got, err := s.Verify(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Recipient != "user@example.com" || got.List != "newsletter" {
+		t.Errorf("token = %+v", got)
+	}
+	if until := time.Until(got.ExpiresAt); until <= 59*time.Minute || until > time.Hour {
+		t.Errorf("ExpiresAt = %s, want about an hour from now", got.ExpiresAt)
+	}
+
+	link, err := s.URL("https://example.com/unsubscribe?src=mail", "user@example.com", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(link)
+	if u.Query().Get("src") != "mail" {
+		t.Errorf("URL = %q, existing query parameters must be kept", link)
+	}
+	if got, err := s.Verify(u.Query().Get("token")); err != nil || got.Recipient != "user@example.com" {
+		t.Errorf("Verify(URL token) = %+v, %v", got, err)
+	}
+}
+
+func TestUnsubscribeSignerRejectsInvalidTokens(t *testing.T) {
+	s := &UnsubscribeSigner{Key: testUnsubscribeKey}
+
+	token, err := s.Token("user@example.com", "newsletter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, signature, _ := strings.Cut(token, ".")
+
+	// 替换负载但保留原签名
+	forged, _ := json.Marshal(unsubscribeClaims{Recipient: "victim@example.com", List: "newsletter"})
+	forgedPayload := base64.RawURLEncoding.EncodeToString(forged)
+
+	// 修改签名的最后一个字符
+	last := signature[len(signature)-1]
+	flipped := byte('A')
+	if last == 'A' {
+		flipped = 'B'
+	}
+	tamperedSignature := signature[:len(signature)-1] + string(flipped)
+
+	otherKey, _ := (&UnsubscribeSigner{Key: []byte("another key of sufficient length!!")}).Token("user@example.com", "newsletter")
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"empty", ""},
+		{"no separator", payload + signature},
+		{"tampered payload", forgedPayload + "." + signature},
+		{"tampered signature", payload + "." + tamperedSignature},
+		{"truncated signature", payload + "." + signature[:10]},
+		{"signature not base64", payload + ".!!!"},
+		{"wrong key", otherKey},
+		{"signature only", "." + signature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Verify(tt.token)
+			if !errors.Is(err, ErrInvalidUnsubscribeToken) {
+				t.Fatalf("Verify = %+v, %v, want ErrInvalidUnsubscribeToken", got, err)
+			}
+			if got != nil {
+				t.Errorf("Verify returned claims for an invalid token: %+v", got)
+			}
+		})
+	}
+
+	// 签名正确但负载不是有效的 JSON
+	garbage := base64.RawURLEncoding.EncodeToString([]byte("not json"))
+	if _, err := s.Verify(garbage + "." + base64.RawURLEncoding.EncodeToString(s.sign(garbage))); !errors.Is(err, ErrInvalidUnsubscribeToken) {
+		t.Errorf("err = %v, want ErrInvalidUnsubscribeToken", err)
+	}
+
+	if _, err := (&UnsubscribeSigner{}).Verify(token); err == nil {
+		t.Error("expected error without key")
+	}
+	if _, err := (&UnsubscribeSigner{}).Token("user@example.com", ""); err == nil {
+		t.Error("expected error without key")
+	}
+}
+
+// expiredToken 生成一个已经过期的令牌
+func expiredToken(s *UnsubscribeSigner, recipient string) string {
+	payload, _ := json.Marshal(unsubscribeClaims{Recipient: recipient, Expires: time.Now().Add(-time.Minute).Unix()})
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(s.sign(encoded))
+}
+
+func TestUnsubscribeSignerExpiredToken(t *testing.T) {
+	s := &UnsubscribeSigner{Key: testUnsubscribeKey, TTL: time.Hour}
+
+	got, err := s.Verify(expiredToken(s, "user@example.com"))
+	if !errors.Is(err, ErrUnsubscribeTokenExpired) || errors.Is(err, ErrInvalidUnsubscribeToken) {
+		t.Fatalf("err = %v, want ErrUnsubscribeTokenExpired", err)
+	}
+	if got == nil || got.Recipient != "user@example.com" {
+		t.Errorf("expired token should still report its claims, got %+v", got)
+	}
+}
+
+func TestUnsubscribeHandler(t *testing.T) {
+	signer := &UnsubscribeSigner{Key: testUnsubscribeKey}
+	token, err := signer.Token(`"><script>@example.com`, "newsletter")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls []*UnsubscribeToken
+	var fail error
+	handler := &UnsubscribeHandler{
+		Signer: signer,
+		OnUnsubscribe: func(ctx context.Context, tok *UnsubscribeToken) error {
+			calls = append(calls, tok)
+			return fail
+		},
+	}
+
+	serve := func(method, token string) *httptest.ResponseRecorder {
+		var body *strings.Reader
+		if method == http.MethodPost {
+			body = strings.NewReader("List-Unsubscribe=One-Click")
+		} else {
+			body = strings.NewReader("")
+		}
+		req := httptest.NewRequest(method, "/unsubscribe?token="+url.QueryEscape(token), body)
+		if method == http.MethodPost {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("GET shows confirmation", func(t *testing.T) {
+		calls = nil
+		rec := serve(http.MethodGet, token)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d", rec.Code)
+		}
+		if len(calls) != 0 {
+			t.Fatal("GET must not unsubscribe")
+		}
+		body := rec.Body.String()
+		if !strings.Contains(body, `<form method="post"`) || !strings.Contains(body, `value="One-Click"`) {
+			t.Errorf("body = %q, want confirmation form", body)
+		}
+		if strings.Contains(body, "<script>") {
+			t.Errorf("recipient must be escaped: %q", body)
+		}
+	})
+
+	t.Run("HEAD does not unsubscribe", func(t *testing.T) {
+		calls = nil
+		if rec := serve(http.MethodHead, token); rec.Code != http.StatusOK || len(calls) != 0 {
+			t.Fatalf("status = %d, calls = %d", rec.Code, len(calls))
+		}
+	})
+
+	t.Run("one-click POST", func(t *testing.T) {
+		calls = nil
+		rec := serve(http.MethodPost, token)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d", rec.Code)
+		}
+		if len(calls) != 1 || calls[0].Recipient != `"><script>@example.com` || calls[0].List != "newsletter" {
+			t.Fatalf("calls = %+v", calls)
+		}
+	})
+
+	t.Run("callback error", func(t *testing.T) {
+		calls, fail = nil, errors.New("database unavailable")
+		defer func() { fail = nil }()
+		if rec := serve(http.MethodPost, token); rec.Code != http.StatusInternalServerError {
+			t.Fatalf("status = %d, want 500", rec.Code)
+		}
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		calls = nil
+		for _, method := range []string{http.MethodGet, http.MethodPost} {
+			if rec := serve(method, token+"x"); rec.Code != http.StatusBadRequest {
+				t.Errorf("%s status = %d, want 400", method, rec.Code)
+			}
+		}
+		if rec := serve(http.MethodPost, ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("missing token status = %d, want 400", rec.Code)
+		}
+		if len(calls) != 0 {
+			t.Fatal("invalid token must not unsubscribe")
+		}
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		calls = nil
+		if rec := serve(http.MethodPost, expiredToken(signer, "user@example.com")); rec.Code != http.StatusGone {
+			t.Errorf("status = %d, want 410", rec.Code)
+		}
+		if len(calls) != 0 {
+			t.Fatal("expired token must not unsubscribe")
+		}
+	})
+
+	t.Run("method not allowed", func(t *testing.T) {
+		rec := serve(http.MethodPut, token)
+		if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") == "" {
+			t.Errorf("status = %d, Allow = %q", rec.Code, rec.Header().Get("Allow"))
+		}
+	})
+}
+
+func TestUnsubscribeHeaders(t *testing.T) {
+	tests := []struct {
+		name     string
+		u        *Unsubscribe
+		wantList string
+		wantPost string
+	}{
+		{"url and mailto", &Unsubscribe{URL: "https://example.com/u?token=abc", Mailto: "unsubscribe@example.com", OneClick: true},
+			"<https://example.com/u?token=abc>, <mailto:unsubscribe@example.com>", "List-Unsubscribe=One-Click"},
+		{"url only", &Unsubscribe{URL: "https://example.com/u"}, "<https://example.com/u>", ""},
+		{"mailto uri", &Unsubscribe{Mailto: "mailto:unsubscribe@example.com?subject=stop"},
+			"<mailto:unsubscribe@example.com?subject=stop>", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Message{
+				From:        mail.Address{Address: "sender@example.com"},
+				To:          []mail.Address{{Address: "to@example.com"}},
+				Text:        "hello",
+				Unsubscribe: tt.u,
+			}
+			if err := m.Validate(); err != nil {
+				t.Fatal(err)
+			}
+
+			h := readHeader(t, string(renderBytes(t, m)))
+			if got := h.Get("List-Unsubscribe"); got != tt.wantList {
+				t.Errorf("List-Unsubscribe = %q, want %q", got, tt.wantList)
+			}
+			if got := h.Get("List-Unsubscribe-Post"); got != tt.wantPost {
+				t.Errorf("List-Unsubscribe-Post = %q, want %q", got, tt.wantPost)
+			}
+
+			// 解析后得到相同的设置
+			parsed := reparse(t, m)
+			if parsed.Unsubscribe == nil || parsed.Unsubscribe.URL != tt.u.URL || parsed.Unsubscribe.OneClick != tt.u.OneClick {
+				t.Errorf("parsed Unsubscribe = %+v", parsed.Unsubscribe)
+			}
+		})
+	}
+}
+
+func TestUnsubscribeValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		u         Unsubscribe
+		wantField string
+	}{
+		{"valid", Unsubscribe{URL: "https://example.com/u", OneClick: true}, ""},
+		{"empty", Unsubscribe{}, "unsubscribe"},
+		{"http url", Unsubscribe{URL: "http://example.com/u"}, "unsubscribe.url"},
+		{"relative url", Unsubscribe{URL: "/unsubscribe"}, "unsubscribe.url"},
+		{"header injection in url", Unsubscribe{URL: "https://example.com/u>\r\nBcc: x@example.com"}, "unsubscribe.url"},
+		{"invalid mailto", Unsubscribe{Mailto: "not an address"}, "unsubscribe.mailto"},
+		{"one-click without url", Unsubscribe{Mailto: "unsubscribe@example.com", OneClick: true}, "unsubscribe.oneClick"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var errs ValidationErrors
+			tt.u.validate(&errs, "unsubscribe")
+
+			if tt.wantField == "" {
+				if len(errs) > 0 {
+					t.Fatalf("unexpected errors: %v", errs)
+				}
+				return
+			}
+
+			for _, e := range errs {
+				if e.Field == tt.wantField {
+					return
+				}
+			}
+			t.Errorf("errors = %v, want one for %s", errs, tt.wantField)
+		})
+	}
+}
@@ -0,0 +1,353 @@
+package gomailer
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidMessage 表示邮件消息未通过校验
+//
+// Message.Validate 返回的 ValidationErrors 满足 errors.Is(err, ErrInvalidMessage)
+var ErrInvalidMessage = errors.New("invalid message")
+
+// ValidationError 描述了邮件消息中的单个问题
+type ValidationError struct {
+	// Field 出现问题的字段，例如 "from"、"to[1]"、"headers[X-Foo]"
+	Field string
+
+	// Message 问题描述
+	Message string
+
+	// Err 可选的底层错误
+	Err error
+}
+
+// Error 实现 error 接口
+func (e *ValidationError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return e.Field + ": " + e.Message
+}
+
+// Unwrap 返回底层错误
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationErrors 是一次校验发现的全部问题
+type ValidationErrors []*ValidationError
+
+// Error 实现 error 接口
+func (e ValidationErrors) Error() string {
+	parts := make([]string, len(e))
+	for i, v := range e {
+		parts[i] = v.Error()
+	}
+	return "invalid message: " + strings.Join(parts, "; ")
+}
+
+// Is 使 errors.Is(err, ErrInvalidMessage) 返回 true
+func (e ValidationErrors) Is(target error) bool {
+	return target == ErrInvalidMessage
+}
+
+// Unwrap 返回所有单个问题，便于使用 errors.Is/errors.As 检查
+func (e ValidationErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, v := range e {
+		errs[i] = v
+	}
+	return errs
+}
+
+// add 追加一个问题
+func (e *ValidationErrors) add(field string, format string, args ...any) {
+	*e = append(*e, &ValidationError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// addErr 追加一个由底层错误描述的问题
+func (e *ValidationErrors) addErr(field string, err error) {
+	*e = append(*e, &ValidationError{Field: field, Message: err.Error(), Err: err})
+}
+
+// Validate 检查邮件消息是否可以发送，并一次性返回发现的所有问题
+//
+// 检查内容包括：
+//   - 发件人是否缺失或无效
+//   - 是否至少有一个收件人（To/Cc/Bcc）
+//   - 收件人地址是否有效，是否存在重复的收件人
+//   - 退订链接是否为 https 地址，退订邮箱是否有效
+//   - 会议邀请的 UID、组织者、参与者和时间是否有效
+//   - 附件的来源、文件名、MIME 类型和 Content-ID 是否有效
+//   - 主题、地址姓名和自定义邮件头中是否包含可用于头部注入的内容（如 CR/LF），
+//     此类问题满足 errors.Is(err, ErrHeaderInjection)
+//   - 字符集是否受支持，文本是否可以用指定的字符集表示，
+//     此类问题满足 errors.Is(err, ErrUnrepresentableCharacter)
+//
+// 返回:
+//   - error: 没有问题时返回 nil，否则返回 ValidationErrors
+func (m *Message) Validate() error {
+	var errs ValidationErrors
+
+	if m == nil {
+		errs.add("", "message is nil")
+		return errs
+	}
+
+	// 发件人
+	if m.From.Address == "" {
+		errs.add("from", "from address is required")
+	} else {
+		validateAddress(&errs, "from", m.From)
+	}
+
+	// 收件人
+	if len(m.To) == 0 && len(m.Cc) == 0 && len(m.Bcc) == 0 {
+		errs.add("", "at least one recipient (To/Cc/Bcc) is required")
+	}
+
+	seen := map[string]string{}
+	for _, group := range []struct {
+		name  string
+		addrs []mail.Address
+	}{
+		{"to", m.To},
+		{"cc", m.Cc},
+		{"bcc", m.Bcc},
+	} {
+		for i, addr := range group.addrs {
+			field := fmt.Sprintf("%s[%d]", group.name, i)
+			if !validateAddress(&errs, field, addr) {
+				continue
+			}
+
+			key := strings.ToLower(addr.Address)
+			if first, ok := seen[key]; ok {
+				errs.add(field, "duplicate recipient %q (already in %s)", addr.Address, first)
+				continue
+			}
+			seen[key] = field
+		}
+	}
+
+	// 回复地址、实际发送者和阅读回执地址
+	for i, addr := range m.ReplyTo {
+		validateAddress(&errs, fmt.Sprintf("replyTo[%d]", i), addr)
+	}
+
+	if m.Sender.Address != "" || m.Sender.Name != "" {
+		validateAddress(&errs, "sender", m.Sender)
+	}
+
+	for i, addr := range m.ReadReceiptTo {
+		validateAddress(&errs, fmt.Sprintf("readReceiptTo[%d]", i), addr)
+	}
+
+	// 会话头
+	if m.MessageID != "" && !validMessageID(m.MessageID) {
+		errs.add("messageId", "invalid message id %q", m.MessageID)
+	}
+
+	if m.InReplyTo != "" && !validMessageID(m.InReplyTo) {
+		errs.add("inReplyTo", "invalid message id %q", m.InReplyTo)
+	}
+
+	for i, id := range m.References {
+		if !validMessageID(id) {
+			errs.add(fmt.Sprintf("references[%d]", i), "invalid message id %q", id)
+		}
+	}
+
+	if !m.Priority.valid() {
+		errs.add("priority", "invalid priority %q", m.Priority)
+	}
+
+	// 头部注入
+	if err := checkHeaderValue("Subject", m.Subject); err != nil {
+		errs.addErr("subject", err)
+	}
+
+	names := make([]string, 0, len(m.Headers))
+	for name := range m.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		field := "headers[" + name + "]"
+		if err := checkHeaderName(name); err != nil {
+			errs.addErr(field, err)
+		} else if isReservedHeader(name) {
+			errs.add(field, "header is generated automatically and cannot be overridden")
+		}
+		if err := checkHeaderValue(name, m.Headers[name]); err != nil {
+			errs.addErr(field, err)
+		}
+	}
+
+	// 字符集
+	m.validateCharsets(&errs, names)
+
+	// 投递状态通知
+	if m.DeliveryNotification != nil {
+		m.DeliveryNotification.validate(&errs, "deliveryNotification")
+	}
+
+	// 退订
+	if m.Unsubscribe != nil {
+		m.Unsubscribe.validate(&errs, "unsubscribe")
+	}
+
+	// 会议邀请
+	if m.Calendar != nil {
+		m.Calendar.validate(&errs, "calendar")
+	}
+
+	// 附件
+	for i := range m.Files {
+		m.Files[i].validate(&errs, fmt.Sprintf("files[%d]", i))
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// validateCharsets 检查字符集是否受支持，以及邮件头和正文能否用对应的字符集表示
+//
+// headerNames 为已排序的自定义邮件头名称
+func (m *Message) validateCharsets(errs *ValidationErrors, headerNames []string) {
+	cs, err := lookupCharset(m.Charset)
+	if err != nil {
+		errs.addErr("charset", err)
+	} else if !cs.utf8() {
+		if _, err := cs.headerText(m.Subject); err != nil {
+			errs.addErr("subject", err)
+		}
+
+		for _, name := range headerNames {
+			if _, err := cs.headerText(m.Headers[name]); err != nil {
+				errs.addErr("headers["+name+"]", err)
+			}
+		}
+
+		for _, group := range []struct {
+			name  string
+			addrs []mail.Address
+		}{
+			{"from", []mail.Address{m.From}},
+			{"sender", []mail.Address{m.Sender}},
+			{"to", m.To},
+			{"cc", m.Cc},
+			{"replyTo", m.ReplyTo},
+			{"readReceiptTo", m.ReadReceiptTo},
+		} {
+			for i, addr := range group.addrs {
+				if _, err := cs.headerText(addr.Name); err != nil {
+					field := group.name
+					if group.name != "from" && group.name != "sender" {
+						field = fmt.Sprintf("%s[%d]", group.name, i)
+					}
+					errs.addErr(field, err)
+				}
+			}
+		}
+	}
+
+	for _, body := range []struct {
+		field        string
+		charsetField string
+		charset      string
+		content      string
+	}{
+		{"text", "textCharset", m.TextCharset, m.Text},
+		{"html", "htmlCharset", m.HTMLCharset, m.HTML},
+	} {
+		bodyCharset := cs
+		if body.charset != "" {
+			if bodyCharset, err = lookupCharset(body.charset); err != nil {
+				errs.addErr(body.charsetField, err)
+				continue
+			}
+		}
+
+		if bodyCharset == nil {
+			// Charset 无效，已经报告过
+			continue
+		}
+
+		if _, err := bodyCharset.encode(body.content); err != nil {
+			errs.addErr(body.field, err)
+		}
+	}
+}
+
+// validateAddress 检查单个邮件地址，有问题时记录到 errs 并返回 false
+func validateAddress(errs *ValidationErrors, field string, addr mail.Address) bool {
+	ok := true
+
+	if err := checkHeaderValue(field, addr.Name); err != nil {
+		errs.addErr(field, err)
+		ok = false
+	}
+
+	if !validAddress(addr.Address) {
+		errs.add(field, "invalid email address %q", addr.Address)
+		ok = false
+	}
+
+	return ok
+}
+
+// validAddress 检查字符串是否为合法的 addr-spec（不含姓名和尖括号）
+func validAddress(address string) bool {
+	if address == "" || strings.ContainsAny(address, "<>") {
+		return false
+	}
+
+	parsed, err := mail.ParseAddress(address)
+	if err != nil {
+		return false
+	}
+
+	return parsed.Name == "" && parsed.Address == address
+}
+
+// validMessageID 检查字符串是否为 "id-left@id-right" 形式的 Message-ID（尖括号可省略）
+func validMessageID(id string) bool {
+	id = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(id), "<"), ">")
+
+	at := strings.Index(id, "@")
+	if at <= 0 || at == len(id)-1 {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if c := id[i]; c <= 32 || c >= 127 || c == '<' || c == '>' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// validHeaderName 检查邮件头字段名是否符合 RFC 5322（可打印 ASCII，不含冒号和空白）
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for i := 0; i < len(name); i++ {
+		if c := name[i]; c < 33 || c > 126 || c == ':' {
+			return false
+		}
+	}
+
+	return true
+}